@@ -0,0 +1,22 @@
+import stomp from 'k6/x/stomp';
+
+export default async function () {
+    // open several connections in parallel without blocking the VU
+    const clients = await Promise.all([
+        stomp.connectAsync({ addr: 'localhost:61613', timeout: '2s' }),
+        stomp.connectAsync({ addr: 'localhost:61613', timeout: '2s' }),
+        stomp.connectAsync('stomp://localhost:61613/?timeout=2s'),
+    ]);
+
+    for (const client of clients) {
+        // send a message to '/my/destination' with text/plain as MIME content-type
+        client.send('my/destination', 'text/plain', 'Hello xk6-stomp!');
+        client.disconnect();
+    }
+
+    try {
+        await stomp.connectAsync({ addr: 'localhost:1', timeout: '1s' });
+    } catch (err) {
+        console.log('connection failed', err);
+    }
+}
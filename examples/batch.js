@@ -0,0 +1,32 @@
+import stomp from 'k6/x/stomp';
+
+const client = stomp.connect({
+    addr: 'localhost:61613',
+    timeout: '2s'
+});
+
+export default function () {
+    const messages = [];
+    for (let i = 0; i < 1000; i++) {
+        messages.push(`message ${i}`);
+    }
+    // all the frames are written from Go, reported as one sample of each stomp_send_* metric
+    const result = client.sendBatch('my/destination', messages, { content_type: 'text/plain' });
+    console.log(result.sent, 'messages sent in', result.batch_time, 'ms');
+
+    // messages can be objects with their own headers and content-type,
+    // the batch headers are added to every message
+    client.sendBatch('my/destination', [
+        'plain body',
+        new Uint8Array([0x08, 0x96, 0x01]),
+        { body: '{"id":1}', contentType: 'application/json', headers: { priority: '9' } },
+    ], {
+        headers: { 'x-batch': `${__ITER}` },
+        // wait for the receipts of all the messages
+        receipt: true,
+    });
+}
+
+export function teardown() {
+    client.disconnect();
+}
@@ -0,0 +1,33 @@
+import stomp from 'k6/x/stomp';
+import { check } from 'k6';
+
+const image = open('./image.png', 'b'); // ArrayBuffer
+
+const client = stomp.connect({
+    addr: 'localhost:61613',
+    timeout: '2s'
+});
+
+export default function () {
+    const subscription = client.subscribe('my/destination');
+
+    // ArrayBuffer, typed array or DataView bodies are sent with content-length,
+    // so they can contain NUL bytes. The content-type defaults to application/octet-stream.
+    client.send('my/destination', 'image/png', image);
+    client.send('my/destination', '', new Uint8Array([0x08, 0x96, 0x01, 0x00]));
+
+    // bytes() returns the message body as an ArrayBuffer
+    const png = subscription.read().bytes();
+    const proto = new Uint8Array(subscription.read().bytes());
+
+    check(null, {
+        'image received': () => png.byteLength === image.byteLength,
+        'protobuf received': () => proto.length === 4 && proto[3] === 0,
+    });
+
+    subscription.unsubscribe();
+}
+
+export function teardown() {
+    client.disconnect();
+}
@@ -0,0 +1,23 @@
+import stomp from 'k6/x/stomp';
+import { check } from 'k6';
+
+export default function () {
+    const client = stomp.connect({
+        addr: 'localhost:61613',
+        heartbeat: {
+            incoming: '10s',
+            outgoing: '10s',
+        },
+    });
+
+    // all the headers of the CONNECTED frame: version, heart-beat, server, session and broker specific ones
+    const headers = client.connectedHeaders();
+    console.log(JSON.stringify(headers));
+
+    check(headers, {
+        'STOMP 1.2': (h) => h['version'] === '1.2',
+        'heart-beats negotiated': (h) => h['heart-beat'] !== undefined && h['heart-beat'] !== '0,0',
+    });
+
+    client.disconnect();
+}
@@ -0,0 +1,18 @@
+import stomp from 'k6/x/stomp';
+
+// open and close a connection on every iteration to load test connection churn
+export default function () {
+    const client = stomp.connect({
+        addr: 'localhost:61613',
+    });
+
+    client.send('my/destination', 'text/plain', 'Hello xk6-stomp!');
+
+    if (__ITER % 2 === 0) {
+        // send DISCONNECT and wait up to 2s for the receipt, the socket is closed after that
+        client.disconnect({ timeout: '2s' });
+    } else {
+        // drop the socket immediately, without DISCONNECT
+        client.disconnect({ force: true });
+    }
+}
@@ -0,0 +1,37 @@
+import stomp from 'k6/x/stomp';
+import { Counter } from 'k6/metrics';
+
+const brokerErrors = new Counter('broker_errors');
+
+export default function () {
+    const client = stomp.connect({
+        addr: 'localhost:61613',
+        reconnect: {
+            max_attempts: 5,
+        },
+    });
+
+    // ERROR frames received from the broker
+    client.on('error', (e) => {
+        brokerErrors.add(1, { message: e.message });
+        console.error(`broker error from ${e.node}: ${e.message}`, e.body);
+    });
+
+    // connection lost or closed, cause is 'client' after client.disconnect()
+    client.on('disconnect', (e) => {
+        console.warn(`disconnected from ${e.node}: ${e.cause}`);
+    });
+
+    client.on('reconnect', (e) => {
+        console.log(`reconnected to ${e.node} after ${e.attempts} attempt(s) in ${e.duration}`);
+    });
+
+    client.on('receipt', (e) => {
+        console.log(`receipt ${e.receipt_id}`);
+    });
+
+    client.send('my/destination', 'text/plain', 'Hello xk6-stomp!', { receipt: true });
+
+    // listeners keep the iteration running until the client is disconnected
+    client.disconnect();
+}
@@ -0,0 +1,29 @@
+import stomp from 'k6/x/stomp';
+
+export const options = {
+    vus: 6,
+    iterations: 60,
+};
+
+// connect to one of the cluster nodes
+const client = stomp.connect({
+    addrs: ['broker-1:61613', 'broker-2:61613', 'broker-3:61613'],
+    // round-robin (default): spread the VUs across the nodes and move to the next node on failure
+    // random: try the nodes in random order
+    // priority: always try the nodes in the given order (primary and backups)
+    addr_strategy: 'round-robin',
+    timeout: '2s',
+    reconnect: {
+        max_attempts: 3,
+    },
+});
+
+export default function () {
+    // the STOMP metrics are tagged with the connected node (e.g. node=broker-2:61613)
+    client.send('my/destination', 'text/plain', 'Hello xk6-stomp!');
+}
+
+export function teardown() {
+    // disconnect from broker
+    client.disconnect();
+}
@@ -0,0 +1,21 @@
+import stomp from 'k6/x/stomp';
+
+export default function () {
+    // connect to broker exchanging heart-beats every 5 seconds
+    const client = stomp.connect({
+        addr: 'localhost:61613',
+        heartbeat: {
+            incoming: '5s',
+            outgoing: '5s',
+        },
+        // called when the broker stops sending heart-beats
+        on_heartbeat_timeout: (e) => {
+            console.warn(`no heart-beat received for ${e.silence} (interval ${e.interval})`);
+            client.disconnect();
+        },
+    });
+
+    client.send('my/destination', 'text/plain', 'Hello xk6-stomp!');
+
+    client.disconnect();
+}
@@ -0,0 +1,45 @@
+import stomp from 'k6/x/stomp';
+
+// random text of 1KB
+const fixed = stomp.payload({ size: 1024 });
+
+// random bytes between 100 and 10000 bytes, the same ones on each run
+const uniform = stomp.payload({ distribution: 'uniform', min: 100, max: 10000, content: 'bytes', seed: 42 });
+
+// random text of about 512 bytes, between 64 and 4096 bytes
+const normal = stomp.payload({ distribution: 'normal', mean: 512, stddev: 128, min: 64, max: 4096 });
+
+// {{random}} is replaced by random content sized like the payloads without template
+const order = stomp.payload({
+    template: '{"id":"{{uuid}}","seq":{{seq}},"vu":{{vu}},"iter":{{iter}},"created":{{timestamp}},"note":"{{random}}"}',
+    size: 32,
+});
+
+const client = stomp.connect({
+    addr: 'localhost:61613',
+    timeout: '2s'
+});
+
+export default function () {
+    // each send generates a new payload
+    client.send('my/destination', 'text/plain', fixed);
+    client.send('my/destination', 'application/json', order);
+
+    // the same generator can be repeated in a batch
+    client.sendBatch('my/destination', Array(100).fill(uniform));
+
+    // next() returns a payload, a string or an ArrayBuffer for bytes content
+    console.log(order.next());
+
+    // producers generate a payload for each message
+    client.startProducer({
+        destination: 'my/destination',
+        rate: 1000,
+        duration: '10s',
+        payload: normal,
+    });
+}
+
+export function teardown() {
+    client.disconnect();
+}
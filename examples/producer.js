@@ -0,0 +1,42 @@
+import stomp from 'k6/x/stomp';
+import { sleep } from 'k6';
+
+export const options = {
+    vus: 1,
+    iterations: 1,
+};
+
+const client = stomp.connect({
+    addr: 'localhost:61613',
+    timeout: '2s'
+});
+
+export default function () {
+    // 10000 messages per second during 1 minute, the iteration waits for the producer to finish
+    const producer = client.startProducer({
+        destination: 'my/destination',
+        rate: 10000,
+        duration: '1m',
+        payload: 'Hello xk6-stomp!',
+        content_type: 'text/plain',
+        headers: { 'x-producer': 'k6' },
+    });
+
+    // ramping from 100 to 5000 messages per second in 30s, stopped explicitly
+    const ramping = client.startProducer({
+        destination: 'my/other/destination',
+        start_rate: 100,
+        rate: 5000,
+        ramp_up: '30s',
+        payload: new Uint8Array([0x08, 0x96, 0x01]),
+    });
+
+    sleep(45);
+    ramping.stop();
+    console.log('ramping producer', JSON.stringify(ramping.stats()));
+    console.log('producer', JSON.stringify(producer.stats()));
+}
+
+export function teardown() {
+    client.disconnect();
+}
@@ -0,0 +1,39 @@
+import stomp from 'k6/x/stomp';
+
+// connect to broker reconnecting automatically when the connection is lost
+const client = stomp.connect({
+    addr: 'localhost:61613',
+    timeout: '2s',
+    heartbeat: {
+        incoming: '10s',
+        outgoing: '10s',
+    },
+    reconnect: {
+        max_attempts: 10,       // negative for unlimited attempts, 0 (default) disables reconnection
+        initial_backoff: '1s',  // doubled after each failed attempt
+        max_backoff: '30s',
+        jitter: 0.2,            // randomize each backoff by up to 20%
+    },
+});
+
+export default function () {
+    // active subscriptions are restored with the same ack mode, id and headers after a reconnect
+    const subscription = client.subscribe('my/destination', { ack: 'client', id: 'my-subscription' });
+
+    // send a message to '/my/destination' with text/plain as MIME content-type
+    client.send('my/destination', 'text/plain', 'Hello xk6-stomp!');
+
+    // read the message
+    const msg = subscription.read();
+
+    // ack the message
+    client.ack(msg);
+
+    // unsubscribe from destination
+    subscription.unsubscribe();
+}
+
+export function teardown() {
+    // disconnect from broker
+    client.disconnect();
+}
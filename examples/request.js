@@ -0,0 +1,36 @@
+import stomp from 'k6/x/stomp';
+import { check } from 'k6';
+
+const client = stomp.connect({
+    addr: 'localhost:61613',
+    timeout: '2s'
+});
+
+export default function () {
+    // the request is sent with reply-to and a generated correlation-id header,
+    // the reply with the same correlation-id is returned
+    const reply = client.request('/queue/rpc', JSON.stringify({ operation: 'ping' }), {
+        content_type: 'application/json',
+        timeout: '5s',
+    });
+    check(reply, {
+        'pong received': (r) => r.json('result') === 'pong',
+    });
+
+    try {
+        // replies sent to a fixed queue, instead of the client temporary queue
+        const order = client.request('/queue/orders', 'order 1', {
+            reply_to: `/queue/replies-${__VU}`,
+            timeout: '2s',
+            headers: { priority: '9' },
+        });
+        console.log(order.string());
+    } catch (err) {
+        // no reply before the timeout, counted by stomp_request_timeout_count
+        console.log('request failed', err);
+    }
+}
+
+export function teardown() {
+    client.disconnect();
+}
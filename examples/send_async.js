@@ -0,0 +1,35 @@
+import stomp from 'k6/x/stomp';
+import { check } from 'k6';
+
+const client = stomp.connect({
+    addr: 'localhost:61613',
+    timeout: '2s',
+    // maximum time waiting for each RECEIPT frame
+    receipt_timeout: '5s'
+});
+
+export default async function () {
+    // sendAsync asks the broker for a receipt and returns a Promise resolved when it arrives,
+    // so many confirmed messages can be in flight at the same time
+    const pending = [];
+    for (let i = 0; i < 100; i++) {
+        pending.push(client.sendAsync('my/destination', 'text/plain', `message ${i}`));
+    }
+
+    const results = await Promise.allSettled(pending);
+    check(results, {
+        'all messages confirmed': (r) => r.every((result) => result.status === 'fulfilled'),
+    });
+
+    try {
+        const receipt = await client.sendAsync('my/destination', 'text/plain', 'last message');
+        console.log(receipt.receipt_id, 'confirmed in', receipt.receipt_time, 'ms');
+    } catch (err) {
+        // ERROR frame, receipt timeout or connection lost
+        console.log('message not confirmed', err);
+    }
+}
+
+export function teardown() {
+    client.disconnect();
+}
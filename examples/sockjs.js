@@ -0,0 +1,32 @@
+import stomp from 'k6/x/stomp';
+
+export default function () {
+
+    // connect to a Spring STOMP endpoint exposed with SockJS (registry.addEndpoint("/gs-guide-websocket").withSockJS())
+    const client = stomp.connect({
+        protocol: 'sockjs',
+        addr: 'localhost:8080',
+        path: '/gs-guide-websocket',
+        // tls: true, // use https and wss
+        // sockjs_transport: 'xhr-streaming', // websocket or xhr-streaming, default is detected from the /info endpoint
+        timeout: '2s',
+    });
+    // or: stomp.connect('sockjs://localhost:8080/gs-guide-websocket?timeout=2s');
+
+    // subscribe to receive messages from '/topic/greetings' with auto ack
+    let subscription = client.subscribe('/topic/greetings');
+
+    // SockJS only carries text: bodies that aren't valid UTF-8, like binary ArrayBuffers, are rejected
+    // send a message to '/app/hello' with application/json as MIME content-type
+    let payload = {name: `WW ${__VU}_${__ITER}`};
+    client.send('/app/hello', 'application/json', JSON.stringify(payload));
+
+    // read the message
+    const msg = subscription.read();
+
+    // show the message
+    console.log('msg', msg.json().content);
+
+    // disconnect from the SockJS session
+    client.disconnect();
+}
@@ -0,0 +1,26 @@
+import stomp from 'k6/x/stomp';
+
+// emulate a slow consumer on a poor mobile link
+const client = stomp.connect({
+    addr: 'localhost:61613',
+    throttle: {
+        read_rate: 16 * 1024,  // bytes per second read from the network
+        write_rate: 8 * 1024,  // bytes per second written to the network
+        latency: '150ms',      // added before each frame sent and received
+    },
+});
+
+export default function () {
+    const subscription = client.subscribe('my/destination', { ack: 'client' });
+
+    client.send('my/destination', 'text/plain', 'Hello xk6-stomp!');
+
+    const msg = subscription.read();
+    client.ack(msg);
+
+    subscription.unsubscribe();
+}
+
+export function teardown() {
+    client.disconnect();
+}
@@ -0,0 +1,45 @@
+import stomp from 'k6/x/stomp';
+import http from 'k6/http';
+
+// fetch a short lived access token, called before each connect and reconnect
+function accessToken() {
+    const res = http.post('https://auth.example.com/oauth/token', {
+        grant_type: 'client_credentials',
+        client_id: __ENV.CLIENT_ID,
+        client_secret: __ENV.CLIENT_SECRET,
+    });
+    return res.json('access_token');
+}
+
+export default function () {
+    // token sent as the passcode of the CONNECT frame
+    const client = stomp.connect({
+        addr: 'localhost:61613',
+        user: 'service-account',
+        token: {
+            provider: accessToken,
+        },
+        reconnect: {
+            max_attempts: -1,
+        },
+    });
+
+    // token sent as 'Authorization: Bearer <token>' on the WebSocket upgrade request
+    const ws = stomp.connect({
+        protocol: 'wss',
+        addr: 'broker.example.com',
+        path: '/ws',
+        token: {
+            value: __ENV.TOKEN,       // static token
+            target: 'ws_header',      // passcode (default), header (CONNECT frame) or ws_header
+            header: 'Authorization',  // default
+            prefix: 'Bearer ',        // default for the Authorization header
+        },
+    });
+
+    client.send('my/destination', 'text/plain', 'Hello xk6-stomp!');
+    ws.send('my/destination', 'text/plain', 'Hello xk6-stomp!');
+
+    client.disconnect();
+    ws.disconnect();
+}
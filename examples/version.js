@@ -0,0 +1,25 @@
+import stomp from 'k6/x/stomp';
+import { check } from 'k6';
+
+export default function () {
+    // accept only STOMP 1.0 to reproduce a legacy client (also ?accept_version=1.0 in a broker URL)
+    const client = stomp.connect({
+        addr: 'localhost:61613',
+        accept_version: '1.0', // comma separated, e.g. '1.1,1.2'
+    });
+
+    check(client, {
+        'negotiated STOMP 1.0': (c) => c.version() === '1.0',
+    });
+
+    const subscription = client.subscribe('my/destination', { ack: 'client' });
+    client.send('my/destination', 'text/plain', 'Hello xk6-stomp!');
+
+    // ACK references the message-id on 1.0 and 1.1 and the ack header on 1.2
+    // (NACK is not supported by STOMP 1.0)
+    const msg = subscription.read();
+    client.ack(msg);
+
+    subscription.unsubscribe();
+    client.disconnect();
+}
@@ -8,6 +8,11 @@ export default function () {
         addr: 'localhost:8080',
         path: '/gs-guide-websocket/websocket',
         timeout: '2s',
+        ws: {
+            subprotocols: ['v12.stomp'], // default: v10.stomp, v11.stomp and v12.stomp
+            message_type: 'text',        // text (default) or binary
+            compression: true,           // permessage-deflate, reports stomp_ws_wire_data_sent and stomp_ws_wire_data_received
+        },
         heartbeat: {
             incoming: '30s',
             outgoing: '30s',
@@ -16,6 +21,9 @@ export default function () {
         receipt_timeout: '10s'
     });
 
+    // show the negotiated subprotocol
+    console.log('subprotocol', client.subprotocol());
+
     // subscribe to receive messages from '/topic/greetings' with auto ack
     let subscription = client.subscribe('/topic/greetings'); 
 
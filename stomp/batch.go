@@ -0,0 +1,194 @@
+package stomp
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-stomp/stomp/v3"
+	"github.com/go-stomp/stomp/v3/frame"
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/metrics"
+)
+
+// BatchOptions configures sendBatch. Headers are added to every message and
+// ContentType is used by the messages without their own. Receipt waits for
+// the receipts of all the messages.
+type BatchOptions struct {
+	Headers     map[string]string
+	ContentType string
+	Receipt     bool
+}
+
+type batchMessage struct {
+	contentType string
+	body        []byte
+	opts        []func(*frame.Frame) error
+}
+
+// messages converts the array of bodies or {body, headers, contentType} objects.
+func (o *BatchOptions) messages(rt *sobek.Runtime, v sobek.Value) ([]batchMessage, error) {
+	var values []sobek.Value
+	if common.IsNullish(v) {
+		return nil, fmt.Errorf("messages should be an array")
+	}
+	if err := rt.ExportTo(v, &values); err != nil {
+		return nil, fmt.Errorf("messages should be an array: %w", err)
+	}
+
+	batch := make([]batchMessage, len(values))
+	for i, value := range values {
+		contentType := o.ContentType
+		headers := o.Headers
+		if obj, ok := value.(*sobek.Object); ok && obj.Get("body") != nil {
+			value = obj.Get("body")
+			for _, key := range []string{"contentType", "content_type"} {
+				if ct := obj.Get(key); !common.IsNullish(ct) {
+					contentType = ct.String()
+				}
+			}
+			if h := obj.Get("headers"); !common.IsNullish(h) {
+				var own map[string]string
+				if err := rt.ExportTo(h, &own); err != nil {
+					return nil, fmt.Errorf("message %d: invalid headers: %w", i, err)
+				}
+				headers = make(map[string]string, len(o.Headers)+len(own))
+				for k, v := range o.Headers {
+					headers[k] = v
+				}
+				for k, v := range own {
+					headers[k] = v
+				}
+			}
+		}
+		body, binary, err := messageBody(rt, value)
+		if err != nil {
+			return nil, fmt.Errorf("message %d: %w", i, err)
+		}
+		if binary && contentType == "" {
+			contentType = binaryContentType
+		}
+		sendOpts := SendOptions{Headers: headers}
+		batch[i] = batchMessage{
+			contentType: contentType,
+			body:        body,
+			opts:        sendOpts.frameOptions(body, binary),
+		}
+	}
+	return batch, nil
+}
+
+// SendBatch sends all the messages to the destination from Go, optionally
+// waiting for all their receipts. The messages are reported as one sample
+// of each stomp_send_* metric and the whole batch by stomp_batch_time.
+func (c *Client) SendBatch(destination string, messages sobek.Value, opts *BatchOptions) map[string]any {
+	c.events.dispatch()
+	rt := c.vu.Runtime()
+	if c.stompConn() == nil {
+		common.Throw(rt, ErrNotConnected)
+	}
+	if c.ctx.Err() != nil || c.vu.Context().Err() != nil || c.vu.State() == nil {
+		return nil
+	}
+	if opts == nil {
+		opts = new(BatchOptions)
+	}
+	batch, err := opts.messages(rt, messages)
+	if err != nil {
+		common.Throw(rt, err)
+	}
+	for i, m := range batch {
+		if err := c.checkBody(m.body); err != nil {
+			common.Throw(rt, fmt.Errorf("message %d: %w", i, err))
+		}
+	}
+
+	startedAt := time.Now()
+	sent, writeTime, err := c.sendBatch(destination, batch, opts.Receipt)
+	now := time.Now()
+
+	tags := map[string]string{
+		METRIC_TAG_QUEUE: destination,
+	}
+	c.reportStats(c.metrics.batchTiming, tags, now, metrics.D(now.Sub(startedAt)))
+	if sent > 0 {
+		c.reportStats(c.metrics.sendMessage, tags, now, float64(sent))
+		c.reportStats(c.metrics.sendMessageTiming, tags, now, metrics.D(writeTime/time.Duration(sent)))
+	}
+	if err != nil {
+		c.reportStats(c.metrics.sendMessageErrors, tags, now, float64(max(len(batch)-sent, 1)))
+		common.Throw(rt, err)
+	}
+	if opts.Receipt {
+		c.reportStats(c.metrics.receiptTiming, tags, now, metrics.D(now.Sub(startedAt)))
+	}
+	return map[string]any{
+		"sent":       sent,
+		"batch_time": metrics.D(now.Sub(startedAt)),
+	}
+}
+
+// sendBatch writes the messages and waits for their receipts. It returns the
+// number of messages written and the time spent writing them. A lost
+// connection is reconnected once, sending the remaining messages on the new
+// one, unless receipts are expected for messages already written on the lost
+// connection. It must be called on the event loop.
+func (c *Client) sendBatch(destination string, batch []batchMessage, receipt bool) (int, time.Duration, error) {
+	type pendingReceipt struct {
+		transport *monitorReadWriteClose
+		id        string
+	}
+	var (
+		receipts []<-chan error
+		pending  []pendingReceipt
+		retried  bool
+	)
+	defer func() {
+		for _, p := range pending {
+			p.transport.receipts.forget(p.id)
+		}
+	}()
+
+	c.mu.RLock()
+	conn, transport := c.conn, c.transport
+	c.mu.RUnlock()
+
+	startedAt := time.Now()
+	sent := 0
+	for sent < len(batch) {
+		m := batch[sent]
+		sendOpts := m.opts
+		if receipt {
+			id := newReceiptID()
+			receipts = append(receipts, transport.receipts.expect(id))
+			pending = append(pending, pendingReceipt{transport, id})
+			sendOpts = append(sendOpts[:len(sendOpts):len(sendOpts)], stomp.SendOpt.Header(asyncReceiptHeader, id))
+		}
+		if err := conn.Send(destination, m.contentType, m.body, sendOpts...); err != nil {
+			if receipt {
+				receipts = receipts[:len(receipts)-1]
+			}
+			if retried || !c.tryReconnect(conn, err) {
+				return sent, time.Since(startedAt), err
+			}
+			if receipt && sent > 0 {
+				// their receipts are lost with the connection, so the batch can't be confirmed
+				return sent, time.Since(startedAt), err
+			}
+			retried = true
+			c.mu.RLock()
+			conn, transport = c.conn, c.transport
+			c.mu.RUnlock()
+			continue
+		}
+		sent++
+	}
+	writeTime := time.Since(startedAt)
+
+	if receipt {
+		if err := c.waitReceipts(receipts...); err != nil {
+			return sent, writeTime, err
+		}
+	}
+	return sent, writeTime, nil
+}
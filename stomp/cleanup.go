@@ -0,0 +1,89 @@
+package stomp
+
+import (
+	"log"
+	"time"
+
+	"go.k6.io/k6/metrics"
+)
+
+// cleanupTimeout bounds the time spent unsubscribing and disconnecting a leaked client.
+const cleanupTimeout = 5 * time.Second
+
+// track registers a connected client to be cleaned up if it is not
+// disconnected before the VU context ends.
+func (s *Stomp) track(c *Client) {
+	s.mu.Lock()
+	s.clients[c] = struct{}{}
+	s.mu.Unlock()
+
+	go func() {
+		<-c.ctx.Done()
+		if s.untrack(c) {
+			c.cleanup()
+		}
+	}()
+}
+
+// untrack removes the client and reports whether it was tracked.
+func (s *Stomp) untrack(c *Client) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.clients[c]
+	delete(s.clients, c)
+	return ok
+}
+
+// cleanup unsubscribes and disconnects a client left connected when the VU context ended.
+func (c *Client) cleanup() {
+	if c.stompConn() == nil {
+		return
+	}
+	c.mu.RLock()
+	subscriptions := make([]*Subscription, 0, len(c.subscriptions))
+	for s := range c.subscriptions {
+		subscriptions = append(subscriptions, s)
+	}
+	c.mu.RUnlock()
+
+	log.Printf("[xk6-stomp] connection to %s was not disconnected, closing it and %d subscription(s)", c.connectedNode(), len(subscriptions))
+	c.reportLeak()
+
+	deadline := time.Now().Add(cleanupTimeout)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, s := range subscriptions {
+			_ = s.Unsubscribe()
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(cleanupTimeout):
+	}
+	remaining := time.Until(deadline)
+	if err := c.disconnect(remaining <= 0, remaining); err != nil {
+		log.Printf("[xk6-stomp] error disconnecting from %s: %s", c.connectedNode(), err)
+	}
+}
+
+// reportLeak counts the leaked client. The VU context is already done,
+// so the sample is pushed directly instead of using reportStats.
+func (c *Client) reportLeak() {
+	state := c.vu.State()
+	if state == nil {
+		return
+	}
+	sample := metrics.Sample{
+		Time: time.Now(),
+		TimeSeries: metrics.TimeSeries{
+			Metric: c.metrics.leakedConnections,
+			Tags:   state.Tags.GetCurrentValues().Tags.With(METRIC_TAG_NODE, c.connectedNode()),
+		},
+		Value: 1,
+	}
+	select {
+	case state.Samples <- sample:
+	case <-time.After(cleanupTimeout):
+	}
+}
@@ -2,4 +2,6 @@ package stomp
 
 const (
 	METRIC_TAG_QUEUE = "queue"
+	METRIC_TAG_NODE  = "node"
+	METRIC_TAG_PHASE = "phase"
 )
@@ -0,0 +1,161 @@
+package stomp
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"time"
+
+	"go.k6.io/k6/lib/netext"
+)
+
+// Connection phases used to tag stomp_connect_error_count.
+const (
+	connectPhaseDNS       = "dns"
+	connectPhaseConnect   = "connect"
+	connectPhaseTLS       = "tls"
+	connectPhaseWSUpgrade = "ws_upgrade"
+	connectPhaseSockJS    = "sockjs"
+	connectPhaseStomp     = "stomp"
+)
+
+// connectTrace collects the duration of each phase of a connection attempt.
+type connectTrace struct {
+	dnsLookup      time.Duration
+	connecting     time.Duration
+	tlsHandshaking time.Duration
+	wsUpgrading    time.Duration
+	stompConnect   time.Duration
+	failedPhase    string
+}
+
+type connectTraceKey struct{}
+
+func withConnectTrace(ctx context.Context, trace *connectTrace) context.Context {
+	return context.WithValue(ctx, connectTraceKey{}, trace)
+}
+
+func connectTraceFrom(ctx context.Context) *connectTrace {
+	if trace, ok := ctx.Value(connectTraceKey{}).(*connectTrace); ok {
+		return trace
+	}
+	return new(connectTrace)
+}
+
+// fail records the phase of the first error.
+func (t *connectTrace) fail(phase string) {
+	if t.failedPhase == "" {
+		t.failedPhase = phase
+	}
+}
+
+// dialContext opens a network connection using the VU dialer, so the k6 options
+// hosts, blockHostnames, blacklistIPs and dns are honored by STOMP connections.
+// Outside a VU (init context) a plain net.Dialer is used.
+func (c *Client) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	trace := connectTraceFrom(ctx)
+	state := c.vu.State()
+	if state == nil || state.Dialer == nil {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			trace.fail(connectPhaseConnect)
+			return nil, err
+		}
+		if net.ParseIP(host) == nil {
+			startedAt := time.Now()
+			ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+			trace.dnsLookup += time.Since(startedAt)
+			if err != nil {
+				trace.fail(connectPhaseDNS)
+				return nil, err
+			}
+			addr = net.JoinHostPort(ips[0].IP.String(), port)
+		}
+		var d net.Dialer
+		startedAt := time.Now()
+		conn, err := d.DialContext(ctx, network, addr)
+		trace.connecting += time.Since(startedAt)
+		if err != nil {
+			trace.fail(connectPhaseConnect)
+		}
+		return conn, err
+	}
+
+	dialer := state.Dialer
+	if d, ok := dialer.(*netext.Dialer); ok && d.Resolver != nil {
+		// the host is resolved once, by the k6 dialer with its DNS options
+		dialer = &netext.Dialer{
+			Dialer:           d.Dialer,
+			Resolver:         &tracedResolver{d.Resolver, trace},
+			Blacklist:        d.Blacklist,
+			BlockedHostnames: d.BlockedHostnames,
+			Hosts:            d.Hosts,
+		}
+	}
+	dnsLookup := trace.dnsLookup
+	startedAt := time.Now()
+	conn, err := dialer.DialContext(ctx, network, addr)
+	trace.connecting += time.Since(startedAt) - (trace.dnsLookup - dnsLookup)
+	if err != nil {
+		trace.fail(connectPhaseConnect)
+		return nil, err
+	}
+	// data_sent and data_received are reported by StatsReadWriteClose
+	if nc, ok := conn.(*netext.Conn); ok {
+		return nc.Conn, nil
+	}
+	return conn, nil
+}
+
+// tracedResolver records the lookups of the k6 dialer as the dns phase.
+type tracedResolver struct {
+	netext.Resolver
+	trace *connectTrace
+}
+
+func (r *tracedResolver) LookupIP(host string) (net.IP, error) {
+	startedAt := time.Now()
+	ip, err := r.Resolver.LookupIP(host)
+	r.trace.dnsLookup += time.Since(startedAt)
+	if err != nil {
+		r.trace.fail(connectPhaseDNS)
+	}
+	return ip, err
+}
+
+// dialTLS opens a TLS connection to the broker.
+func (c *Client) dialTLS(ctx context.Context, opts *Options, addr string) (net.Conn, error) {
+	tlsConfig, err := newTLSConfig(opts, c.vu.State())
+	if err != nil {
+		return nil, err
+	}
+	conn, err := c.dialBroker(ctx, opts.Protocol, addr)
+	if err != nil {
+		return nil, err
+	}
+	return tlsHandshake(ctx, conn, tlsConfig, addr)
+}
+
+// tlsHandshake starts a TLS client session on conn, recording the handshake duration.
+func tlsHandshake(ctx context.Context, conn net.Conn, tlsConfig *tls.Config, addr string) (net.Conn, error) {
+	trace := connectTraceFrom(ctx)
+	if tlsConfig.ServerName == "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		tlsConfig = tlsConfig.Clone()
+		tlsConfig.ServerName = host
+	}
+	startedAt := time.Now()
+	tlsConn := tls.Client(conn, tlsConfig)
+	err := tlsConn.HandshakeContext(ctx)
+	trace.tlsHandshaking += time.Since(startedAt)
+	if err != nil {
+		trace.fail(connectPhaseTLS)
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
@@ -0,0 +1,209 @@
+package stomp
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/go-stomp/stomp/v3/frame"
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/js/common"
+)
+
+// Client events dispatched to the listeners added with Client.On.
+const (
+	eventDisconnect       = "disconnect"
+	eventError            = "error"
+	eventReconnect        = "reconnect"
+	eventReceipt          = "receipt"
+	eventHeartbeatTimeout = "heartbeat_timeout"
+)
+
+var clientEventNames = map[string]bool{
+	eventDisconnect:       true,
+	eventError:            true,
+	eventReconnect:        true,
+	eventReceipt:          true,
+	eventHeartbeatTimeout: true,
+}
+
+// maxQueuedEvents bounds the events of a client connected in the init context
+// waiting for its next call, the newer ones are dropped.
+const maxQueuedEvents = 1000
+
+// EventListener is a callback function executed on the VU event loop when a client event occurs.
+type EventListener func(sobek.Value) (sobek.Value, error)
+
+type clientEvent struct {
+	name    string
+	details map[string]any
+}
+
+// clientEvents delivers the client events to the JS listeners on the VU event loop.
+// Like subscriptions with listeners, while a client connected in a VU context has
+// event listeners the VU iteration only ends after the client is disconnected.
+// Clients connected in the init context outlive the iterations, so their events
+// are delivered on their next call in a VU context instead of holding the iteration.
+type clientEvents struct {
+	client *Client
+	// async is set for clients connected in a VU context
+	async bool
+
+	mu        sync.Mutex
+	listeners map[string][]EventListener
+	queue     []clientEvent
+	signal    chan struct{}
+	listening bool
+	// armed is set while the queued events are going to be delivered: by the
+	// waiting loop of async clients or after the first VU context call of the others
+	armed   bool
+	dropped bool
+}
+
+func newClientEvents(client *Client, async bool) *clientEvents {
+	return &clientEvents{
+		client:    client,
+		async:     async,
+		listeners: make(map[string][]EventListener),
+		signal:    make(chan struct{}, 1),
+	}
+}
+
+// on adds a listener for the event name.
+func (e *clientEvents) on(name string, listener EventListener) {
+	if listener == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.listeners[name] = append(e.listeners[name], listener)
+}
+
+// emit queues an event to be delivered on the event loop. It is safe to call from any goroutine.
+func (e *clientEvents) emit(name string, details map[string]any) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.listeners[name]) == 0 || !e.armed {
+		return
+	}
+	if len(e.queue) >= maxQueuedEvents {
+		if !e.dropped {
+			e.dropped = true
+			log.Printf("[xk6-stomp] more than %d client events waiting for the next call of the client, dropping the newer ones", maxQueuedEvents)
+		}
+		return
+	}
+	e.queue = append(e.queue, clientEvent{name: name, details: details})
+	select {
+	case e.signal <- struct{}{}:
+	default:
+	}
+}
+
+// listen waits for the next events of async clients. It must be called on the event loop.
+func (e *clientEvents) listen() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.async || e.listening || len(e.listeners) == 0 || e.client.vu.State() == nil {
+		return
+	}
+	if e.client.ctx.Err() != nil {
+		e.armed, e.queue = false, nil
+		return
+	}
+	e.listening, e.armed = true, true
+	runOnLoop := e.client.vu.RegisterCallback()
+	go e.wait(runOnLoop)
+}
+
+// dispatch delivers the events queued for a client connected in the init
+// context, arming the queueing on its first call and disarming it once the
+// client is disconnected. The client methods call it on the event loop.
+func (e *clientEvents) dispatch() {
+	if e.async || e.client.vu.State() == nil {
+		return
+	}
+	e.mu.Lock()
+	e.armed = len(e.listeners) > 0 && e.client.ctx.Err() == nil
+	events := e.queue
+	e.queue, e.dropped = nil, false
+	e.mu.Unlock()
+	if err := e.deliver(events); err != nil {
+		common.Throw(e.client.vu.Runtime(), err)
+	}
+}
+
+func (e *clientEvents) wait(runOnLoop func(func() error)) {
+	select {
+	case <-e.signal:
+	case <-e.client.ctx.Done():
+	case <-e.client.vu.Context().Done():
+	}
+	runOnLoop(func() error {
+		e.mu.Lock()
+		events := e.queue
+		e.queue, e.dropped = nil, false
+		e.listening = false
+		e.mu.Unlock()
+
+		if err := e.deliver(events); err != nil {
+			return err
+		}
+		e.listen()
+		return nil
+	})
+}
+
+// deliver calls the listeners of the events on the event loop.
+func (e *clientEvents) deliver(events []clientEvent) error {
+	rt := e.client.vu.Runtime()
+	for _, event := range events {
+		e.mu.Lock()
+		listeners := e.listeners[event.name]
+		e.mu.Unlock()
+		for _, listener := range listeners {
+			if _, err := listener(rt.ToValue(event.details)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// On adds a listener called on the event loop when the event occurs:
+// disconnect, error, reconnect, receipt or heartbeat_timeout.
+func (c *Client) On(event string, listener EventListener) {
+	if !clientEventNames[event] {
+		common.Throw(c.vu.Runtime(), fmt.Errorf("unknown event %q, should be 'disconnect', 'error', 'reconnect', 'receipt' or 'heartbeat_timeout'", event))
+	}
+	if listener == nil {
+		common.Throw(c.vu.Runtime(), fmt.Errorf("listener for %q should be a function", event))
+	}
+	if !c.events.async && c.vu.State() != nil {
+		// each iteration would add the listener again
+		common.Throw(c.vu.Runtime(), errors.New("listeners of a client connected in the init context should be added in the init context"))
+	}
+	c.events.on(event, listener)
+	c.events.listen()
+}
+
+func errorFrameDetails(f *frame.Frame, node string) map[string]any {
+	return map[string]any{
+		"message": f.Header.Get(frame.Message),
+		"headers": headerMap(f.Header),
+		"body":    string(f.Body),
+		"node":    node,
+	}
+}
+
+func headerMap(h *frame.Header) map[string]string {
+	m := make(map[string]string, h.Len())
+	for i := 0; i < h.Len(); i++ {
+		k, v := h.GetAt(i)
+		if _, ok := m[k]; !ok {
+			m[k] = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,66 @@
+package stomp
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+)
+
+const (
+	addrStrategyRoundRobin = "round-robin"
+	addrStrategyRandom     = "random"
+	addrStrategyPriority   = "priority"
+)
+
+func validateAddrs(opts *Options) error {
+	if opts.Addr == "" && len(opts.Addrs) == 0 {
+		return errors.New("addr or addrs is required")
+	}
+	switch opts.AddrStrategy {
+	case "", addrStrategyRoundRobin, addrStrategyRandom, addrStrategyPriority:
+		return nil
+	default:
+		return fmt.Errorf("addr_strategy should be '%s', '%s' or '%s'",
+			addrStrategyRoundRobin, addrStrategyRandom, addrStrategyPriority)
+	}
+}
+
+// brokerAddrs returns the broker addresses in the order they should be tried.
+//
+//   - round-robin: each VU starts on a different address and every new
+//     connection moves to the next one.
+//   - random: the addresses are shuffled on every connection.
+//   - priority: the addresses are always tried in the given order, so the
+//     first one is the primary and the others are backups.
+func (c *Client) brokerAddrs() []string {
+	addrs := make([]string, 0, len(c.opts.Addrs)+1)
+	if c.opts.Addr != "" {
+		addrs = append(addrs, c.opts.Addr)
+	}
+	addrs = append(addrs, c.opts.Addrs...)
+	if len(addrs) < 2 {
+		return addrs
+	}
+
+	switch c.opts.AddrStrategy {
+	case addrStrategyPriority:
+		return addrs
+	case addrStrategyRandom:
+		rand.Shuffle(len(addrs), func(i, j int) {
+			addrs[i], addrs[j] = addrs[j], addrs[i]
+		})
+		return addrs
+	default:
+		start := int(c.nextAddr % uint64(len(addrs)))
+		c.nextAddr++
+		return append(append(make([]string, 0, len(addrs)), addrs[start:]...), addrs[:start]...)
+	}
+}
+
+// connectedNode returns the broker address of the current connection.
+func (c *Client) connectedNode() string {
+	if node := c.node.Load(); node != nil {
+		return *node
+	}
+	return ""
+}
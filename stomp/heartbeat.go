@@ -0,0 +1,364 @@
+package stomp
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-stomp/stomp/v3"
+	"github.com/go-stomp/stomp/v3/frame"
+	"go.k6.io/k6/metrics"
+)
+
+// monitorReadWriteClose follows the frames received from the server to report
+// the heart-beats, ERROR and RECEIPT frames and the connection being closed.
+type monitorReadWriteClose struct {
+	io.ReadWriteCloser
+	client *Client
+	// node is the broker address of the connection
+	node    string
+	scanner frameScanner
+	writer  receiptWriter
+
+	mu            sync.Mutex
+	lastRead      time.Time
+	lastHeartbeat time.Time
+	interval      time.Duration
+	connected     bool
+	readErr       error
+	errorFrame    *frame.Frame
+	receipts      receiptTracker
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func newMonitorReadWriteClose(rwc io.ReadWriteCloser, c *Client, node string) *monitorReadWriteClose {
+	now := time.Now()
+	m := monitorReadWriteClose{
+		ReadWriteCloser: rwc,
+		client:          c,
+		node:            node,
+		lastRead:        now,
+		lastHeartbeat:   now,
+		closed:          make(chan struct{}),
+	}
+	m.scanner = frameScanner{contentLength: -1, onFrame: m.frameReceived}
+	return &m
+}
+
+func (m *monitorReadWriteClose) Read(p []byte) (int, error) {
+	n, err := m.ReadWriteCloser.Read(p)
+	if err != nil {
+		m.mu.Lock()
+		m.readErr = err
+		m.mu.Unlock()
+	}
+	if n > 0 {
+		now := time.Now()
+		heartbeats := m.scanner.scan(p[:n])
+		m.mu.Lock()
+		m.lastRead = now
+		var interval time.Duration
+		if heartbeats > 0 {
+			interval = now.Sub(m.lastHeartbeat)
+			m.lastHeartbeat = now
+		}
+		m.mu.Unlock()
+		if heartbeats > 0 {
+			m.client.reportStats(m.client.metrics.heartbeatReceived, nil, now, float64(heartbeats))
+			m.client.reportStats(m.client.metrics.heartbeatInterval, nil, now, metrics.D(interval))
+		}
+	}
+	return n, err
+}
+
+// Write is only called by the go-stomp writer goroutine.
+func (m *monitorReadWriteClose) Write(p []byte) (int, error) {
+	out, heartbeats := m.writer.rewrite(p)
+	if len(out) > 0 {
+		if _, err := m.ReadWriteCloser.Write(out); err != nil {
+			return 0, err
+		}
+	}
+	if heartbeats > 0 {
+		m.client.reportStats(m.client.metrics.heartbeatSent, nil, time.Now(), float64(heartbeats))
+	}
+	return len(p), nil
+}
+
+func (m *monitorReadWriteClose) frameReceived(f *frame.Frame) {
+	switch f.Command {
+	case frame.ERROR:
+		m.mu.Lock()
+		m.errorFrame = f
+		m.mu.Unlock()
+		m.receipts.fail(stomp.Error{Message: f.Header.Get(frame.Message), Frame: f})
+		m.client.events.emit(eventError, errorFrameDetails(f, m.node))
+	case frame.RECEIPT:
+		m.receipts.received(f.Header.Get(frame.ReceiptId))
+		m.client.events.emit(eventReceipt, map[string]any{
+			"receipt_id": f.Header.Get(frame.ReceiptId),
+			"headers":    headerMap(f.Header),
+			"node":       m.node,
+		})
+	}
+}
+
+// established marks the STOMP session as connected, closing the connection
+// after that is reported as a disconnect.
+func (m *monitorReadWriteClose) established() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.connected = true
+}
+
+func (m *monitorReadWriteClose) Close() error {
+	m.closeOnce.Do(func() {
+		close(m.closed)
+		m.receipts.fail(stomp.ErrClosedUnexpectedly)
+		if m.client.ctx.Err() != nil {
+			return
+		}
+		m.mu.Lock()
+		connected, interval, silence := m.connected, m.interval, time.Since(m.lastRead)
+		readErr, errorFrame := m.readErr, m.errorFrame
+		m.mu.Unlock()
+		if !connected {
+			return
+		}
+
+		details := map[string]any{"node": m.node}
+		switch {
+		case errorFrame != nil:
+			details["cause"] = "error frame: " + errorFrame.Header.Get(frame.Message)
+			details["error"] = errorFrameDetails(errorFrame, m.node)
+		// go-stomp closes the connection when the read timeout expires
+		case interval > 0 && silence >= interval:
+			details["cause"] = "heart-beat timeout"
+			m.client.events.emit(eventHeartbeatTimeout, map[string]any{
+				"interval": interval.String(),
+				"silence":  silence.String(),
+				"node":     m.node,
+			})
+		case readErr != nil:
+			details["cause"] = readErr.Error()
+		default:
+			details["cause"] = "connection closed"
+		}
+		m.client.events.emit(eventDisconnect, details)
+	})
+	return m.ReadWriteCloser.Close()
+}
+
+// monitor counts the intervals the server didn't send any data after the
+// incoming heart-beat interval negotiated on CONNECTED.
+func (m *monitorReadWriteClose) monitor(interval time.Duration) {
+	m.mu.Lock()
+	m.interval = interval
+	m.mu.Unlock()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.mu.Lock()
+			silence := time.Since(m.lastRead)
+			m.mu.Unlock()
+			if silence > interval+interval/2 {
+				m.client.reportStats(m.client.metrics.heartbeatMissed, nil, time.Now(), 1)
+			}
+		case <-m.closed:
+			return
+		case <-m.client.ctx.Done():
+			return
+		}
+	}
+}
+
+func (o *Options) heartbeatEnabled() bool {
+	return o.Heartbeat.Incoming != "" || o.Heartbeat.Outgoing != ""
+}
+
+// incomingHeartbeat returns the heart-beat interval requested to the server,
+// go-stomp defaults to one minute.
+func (o *Options) incomingHeartbeat() time.Duration {
+	if o.Heartbeat.Incoming == "" {
+		return time.Minute
+	}
+	d, _ := time.ParseDuration(o.Heartbeat.Incoming)
+	return d
+}
+
+// negotiatedIncomingHeartbeat returns the interval the server should send heart-beats,
+// from the CONNECTED heart-beat header and the interval requested by the client.
+func negotiatedIncomingHeartbeat(header *frame.Header, requested time.Duration) time.Duration {
+	if header == nil {
+		return 0
+	}
+	value, ok := header.Contains(frame.HeartBeat)
+	if !ok {
+		return 0
+	}
+	serverSend, _, err := frame.ParseHeartBeat(value)
+	if err != nil || serverSend == 0 || requested == 0 {
+		return 0
+	}
+	return max(serverSend, requested)
+}
+
+const (
+	scanFrameStart = iota
+	scanHeaders
+	scanBodyLength
+	scanBodyNUL
+)
+
+// maxCapturedFrame limits the size of the ERROR and RECEIPT frames passed to onFrame.
+const maxCapturedFrame = 64 * 1024
+
+// frameScanner follows the STOMP frame boundaries of a byte stream to count
+// the heart-beats (EOLs) received between frames. ERROR and RECEIPT frames
+// are parsed and passed to onFrame.
+type frameScanner struct {
+	state         int
+	line          []byte
+	command       bool
+	contentLength int
+	remaining     int
+
+	onFrame func(*frame.Frame)
+	capture bool
+	raw     []byte
+}
+
+func (s *frameScanner) scan(p []byte) (heartbeats int) {
+	_, heartbeats = s.advance(p, false)
+	return heartbeats
+}
+
+// frameStart scans p up to the first byte of the next frame and returns its
+// offset, or len(p) when no frame starts in p. The first byte of the frame is
+// left to be scanned.
+func (s *frameScanner) frameStart(p []byte) int {
+	n, _ := s.advance(p, true)
+	return n
+}
+
+// advance scans p, stopping before the first byte of a frame when stopAtFrame
+// is set, and returns the number of bytes scanned.
+func (s *frameScanner) advance(p []byte, stopAtFrame bool) (scanned, heartbeats int) {
+	size := len(p)
+	for len(p) > 0 {
+		switch s.state {
+		case scanFrameStart:
+			switch p[0] {
+			case '\n':
+				heartbeats++
+			case '\r':
+			default:
+				if stopAtFrame {
+					return size - len(p), heartbeats
+				}
+				s.state = scanHeaders
+				s.command = true
+				s.contentLength = -1
+				s.line = s.line[:0]
+				continue
+			}
+			p = p[1:]
+		case scanHeaders:
+			i := bytes.IndexByte(p, '\n')
+			if i < 0 {
+				s.appendLine(p)
+				s.captureBytes(p)
+				return size, heartbeats
+			}
+			s.appendLine(p[:i])
+			s.captureBytes(p[:i+1])
+			p = p[i+1:]
+			line := bytes.TrimSuffix(s.line, []byte("\r"))
+			s.line = s.line[:0]
+			if s.command {
+				s.command = false
+				s.startCapture(line)
+				continue
+			}
+			if len(line) == 0 {
+				if s.contentLength >= 0 {
+					s.state, s.remaining = scanBodyLength, s.contentLength+1
+				} else {
+					s.state = scanBodyNUL
+				}
+				continue
+			}
+			if value, ok := bytes.CutPrefix(line, []byte(frame.ContentLength+":")); ok && s.contentLength < 0 {
+				if n, err := strconv.Atoi(string(value)); err == nil {
+					s.contentLength = n
+				}
+			}
+		case scanBodyLength:
+			n := min(s.remaining, len(p))
+			s.captureBytes(p[:n])
+			s.remaining -= n
+			p = p[n:]
+			if s.remaining == 0 {
+				s.frameEnd()
+			}
+		case scanBodyNUL:
+			i := bytes.IndexByte(p, 0)
+			if i < 0 {
+				s.captureBytes(p)
+				return size, heartbeats
+			}
+			s.captureBytes(p[:i+1])
+			p = p[i+1:]
+			s.frameEnd()
+		}
+	}
+	return size, heartbeats
+}
+
+// appendLine keeps only the beginning of header lines, enough to find the command and content-length.
+func (s *frameScanner) appendLine(p []byte) {
+	const maxLine = 64
+	if room := maxLine - len(s.line); room > 0 {
+		s.line = append(s.line, p[:min(room, len(p))]...)
+	}
+}
+
+func (s *frameScanner) startCapture(command []byte) {
+	s.capture = false
+	if s.onFrame == nil {
+		return
+	}
+	if cmd := string(command); cmd == frame.ERROR || cmd == frame.RECEIPT {
+		s.capture = true
+		s.raw = append(append(s.raw[:0], command...), '\n')
+	}
+}
+
+func (s *frameScanner) captureBytes(p []byte) {
+	if !s.capture {
+		return
+	}
+	if len(s.raw)+len(p) > maxCapturedFrame {
+		s.capture = false
+		return
+	}
+	s.raw = append(s.raw, p...)
+}
+
+func (s *frameScanner) frameEnd() {
+	s.state = scanFrameStart
+	if !s.capture {
+		return
+	}
+	s.capture = false
+	if f, err := frame.NewReader(bytes.NewReader(s.raw)).Read(); err == nil && f != nil {
+		s.onFrame(f)
+	}
+}
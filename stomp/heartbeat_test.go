@@ -0,0 +1,85 @@
+package stomp
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-stomp/stomp/v3/frame"
+)
+
+func TestFrameScannerScan(t *testing.T) {
+	body := "line 1\nline 2\r\n\n\x00after the null\n"
+	tests := []struct {
+		name       string
+		in         string
+		heartbeats int
+		// frames are the commands and receipt-id or message headers passed to onFrame
+		frames []string
+	}{
+		{
+			name:       "heart-beats",
+			in:         "\n\n\n",
+			heartbeats: 3,
+		},
+		{
+			name:       "crlf heart-beats",
+			in:         "\r\n\r\n",
+			heartbeats: 2,
+		},
+		{
+			name:       "heart-beats between frames",
+			in:         "\nMESSAGE\ndestination:/q\n\nhello\x00\n\r\nMESSAGE\n\n\x00\n",
+			heartbeats: 4,
+		},
+		{
+			name:       "content-length body with EOLs and null bytes",
+			in:         "MESSAGE\ncontent-length:" + strconv.Itoa(len(body)) + "\n\n" + body + "\x00\n",
+			heartbeats: 1,
+		},
+		{
+			name:       "body without content-length ends at the null byte",
+			in:         "MESSAGE\n\n\n\n\x00\n",
+			heartbeats: 1,
+		},
+		{
+			name:       "crlf headers",
+			in:         "MESSAGE\r\ndestination:/q\r\n\r\n\n\x00\r\n",
+			heartbeats: 1,
+		},
+		{
+			name:       "receipt and error frames",
+			in:         "RECEIPT\nreceipt-id:7\n\n\x00\nERROR\nmessage:boom\ncontent-length:4\n\nab\n\x00\x00\n",
+			heartbeats: 2,
+			frames:     []string{"RECEIPT 7", "ERROR boom"},
+		},
+		{
+			name:   "long header lines",
+			in:     "RECEIPT\nx-padding:" + strings.Repeat("x", 1000) + "\nreceipt-id:8\n\n\x00",
+			frames: []string{"RECEIPT 8"},
+		},
+	}
+	for _, tt := range tests {
+		for _, chunk := range []int{1, 2, 5, len(tt.in)} {
+			var frames []string
+			s := frameScanner{contentLength: -1, onFrame: func(f *frame.Frame) {
+				frames = append(frames, f.Command+" "+f.Header.Get(frame.ReceiptId)+f.Header.Get(frame.Message))
+			}}
+			heartbeats := 0
+			for in := []byte(tt.in); len(in) > 0; {
+				n := min(chunk, len(in))
+				heartbeats += s.scan(in[:n])
+				in = in[n:]
+			}
+			if heartbeats != tt.heartbeats {
+				t.Errorf("%s, %d bytes reads: got %d heart-beats, want %d", tt.name, chunk, heartbeats, tt.heartbeats)
+			}
+			if strings.Join(frames, ",") != strings.Join(tt.frames, ",") {
+				t.Errorf("%s, %d bytes reads: got frames %q, want %q", tt.name, chunk, frames, tt.frames)
+			}
+			if s.state != scanFrameStart {
+				t.Errorf("%s, %d bytes reads: scanner left in the middle of a frame", tt.name, chunk)
+			}
+		}
+	}
+}
@@ -1,10 +1,13 @@
 package stomp
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/go-stomp/stomp/v3"
+	"github.com/go-stomp/stomp/v3/frame"
 	"github.com/grafana/sobek"
 	"github.com/tidwall/gjson"
 	"go.k6.io/k6/js/common"
@@ -24,6 +27,11 @@ func (m *Message) String() string {
 	return string(m.Body)
 }
 
+// Bytes returns the message body as an ArrayBuffer.
+func (m *Message) Bytes() sobek.ArrayBuffer {
+	return m.vu.Runtime().NewArrayBuffer(m.Body)
+}
+
 func (m *Message) JSON(selector ...string) sobek.Value {
 	rt := m.vu.Runtime()
 	if m.vu.State() == nil {
@@ -68,3 +76,87 @@ func (m *Message) JSON(selector ...string) sobek.Value {
 
 	return rt.ToValue(m.cachedJSON)
 }
+
+// binaryContentType is the content-type of binary bodies sent without one.
+const binaryContentType = "application/octet-stream"
+
+// messageBody returns the bytes of a string, ArrayBuffer, typed array, DataView
+// or payload generator body and whether the body is binary. The bytes of
+// binary bodies are copied: go-stomp writes them after the JS code goes on.
+func messageBody(rt *sobek.Runtime, v sobek.Value) ([]byte, bool, error) {
+	if common.IsNullish(v) {
+		return nil, false, nil
+	}
+	switch body := v.Export().(type) {
+	case string:
+		return []byte(body), false, nil
+	case sobek.ArrayBuffer:
+		return bytes.Clone(body.Bytes()), true, nil
+	case *Payload:
+		return body.generate(body.env()), body.binary, nil
+	}
+	// typed arrays and DataView are views of an ArrayBuffer
+	if obj, ok := v.(*sobek.Object); ok && isArrayBufferView(rt, obj) {
+		buffer, ok := exportValue(obj.Get("buffer")).(sobek.ArrayBuffer)
+		if !ok {
+			return nil, false, errors.New("invalid body, the buffer of the view is not an ArrayBuffer")
+		}
+		data := buffer.Bytes()
+		offset, length := intValue(obj.Get("byteOffset")), intValue(obj.Get("byteLength"))
+		if offset < 0 || length < 0 || offset > int64(len(data)) || length > int64(len(data))-offset {
+			return nil, false, fmt.Errorf("invalid body, byteOffset %d and byteLength %d out of the %d bytes buffer",
+				offset, length, len(data))
+		}
+		return bytes.Clone(data[offset : offset+length]), true, nil
+	}
+	return nil, false, fmt.Errorf("invalid body type %T, expected string, ArrayBuffer or typed array", v.Export())
+}
+
+// isArrayBufferView reports whether obj is a typed array or a DataView.
+func isArrayBufferView(rt *sobek.Runtime, obj *sobek.Object) bool {
+	arrayBuffer, ok := rt.Get("ArrayBuffer").(*sobek.Object)
+	if !ok {
+		return false
+	}
+	isView, ok := sobek.AssertFunction(arrayBuffer.Get("isView"))
+	if !ok {
+		return false
+	}
+	result, err := isView(sobek.Undefined(), obj)
+	return err == nil && result.ToBoolean()
+}
+
+// intValue returns the integer value of v, -1 for missing properties.
+func intValue(v sobek.Value) int64 {
+	if common.IsNullish(v) {
+		return -1
+	}
+	return v.ToInteger()
+}
+
+// exportValue exports v, returning nil for missing properties.
+func exportValue(v sobek.Value) any {
+	if v == nil {
+		return nil
+	}
+	return v.Export()
+}
+
+// ackHeaders completes the message headers go-stomp uses to build ACK and NACK
+// frames for the negotiated version: STOMP 1.2 references the ack header of the
+// MESSAGE frame, while 1.0 and 1.1 reference the message-id.
+func ackHeaders(version stomp.Version, h *frame.Header) {
+	if h == nil {
+		return
+	}
+	switch version {
+	case stomp.V12:
+		if _, ok := h.Contains(frame.Ack); !ok && h.Get(frame.MessageId) != "" {
+			h.Set(frame.Ack, h.Get(frame.MessageId))
+		}
+	default:
+		if _, ok := h.Contains(frame.MessageId); !ok && h.Get(frame.Ack) != "" {
+			h.Set(frame.MessageId, h.Get(frame.Ack))
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package stomp
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/grafana/sobek"
+)
+
+func TestMessageBody(t *testing.T) {
+	tests := []struct {
+		name   string
+		js     string
+		want   []byte
+		binary bool
+		err    bool
+	}{
+		{name: "string", js: `"hello"`, want: []byte("hello")},
+		{name: "null", js: `null`},
+		{name: "ArrayBuffer", js: `new Uint8Array([1, 2, 3]).buffer`, want: []byte{1, 2, 3}, binary: true},
+		{name: "typed array view", js: `new Uint8Array([1, 2, 3, 4, 5]).subarray(1, 4)`, want: []byte{2, 3, 4}, binary: true},
+		{name: "multi-byte typed array", js: `new Uint16Array([0x0201, 0x0403])`, want: []byte{1, 2, 3, 4}, binary: true},
+		{name: "DataView", js: `new DataView(new Uint8Array([1, 2, 3, 4]).buffer, 1, 2)`, want: []byte{2, 3}, binary: true},
+		{name: "object with a buffer", js: `({buffer: new ArrayBuffer(8)})`, err: true},
+		{name: "object with view properties", js: `({buffer: new ArrayBuffer(8), byteOffset: 4, byteLength: 100})`, err: true},
+		{
+			name: "view with a bogus offset",
+			js:   `Object.defineProperty(new Uint8Array(4), "byteOffset", {value: 10})`,
+			err:  true,
+		},
+		{name: "number", js: `42`, err: true},
+	}
+	for _, tt := range tests {
+		rt := sobek.New()
+		v, err := rt.RunString(tt.js)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		body, binary, err := messageBody(rt, v)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %v", tt.name, body)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if !bytes.Equal(body, tt.want) || binary != tt.binary {
+			t.Errorf("%s: got %v (binary %v), want %v (binary %v)", tt.name, body, binary, tt.want, tt.binary)
+		}
+	}
+}
+
+func TestMessageBodyCopiesJSMemory(t *testing.T) {
+	rt := sobek.New()
+	v, err := rt.RunString(`var data = new Uint8Array([1, 2, 3]); data`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _, err := messageBody(rt, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rt.RunString(`data.fill(9)`); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(body, []byte{1, 2, 3}) {
+		t.Errorf("body changed with the typed array: %v", body)
+	}
+}
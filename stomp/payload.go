@@ -0,0 +1,248 @@
+package stomp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/modules"
+)
+
+const (
+	payloadDistributionFixed   = "fixed"
+	payloadDistributionUniform = "uniform"
+	payloadDistributionNormal  = "normal"
+
+	payloadContentText  = "text"
+	payloadContentBytes = "bytes"
+
+	payloadTextChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+)
+
+// payloadPlaceholders are the template placeholders, {{random}} is the
+// random content sized by the distribution.
+var payloadPlaceholders = map[string]struct{}{
+	"uuid":      {},
+	"seq":       {},
+	"vu":        {},
+	"iter":      {},
+	"timestamp": {},
+	"random":    {},
+}
+
+// PayloadOptions configures a payload generator. Without Template each
+// payload is random Content (text or bytes) of a size following the
+// Distribution: Size bytes (fixed), between Min and Max (uniform) or around
+// Mean with Stddev (normal, limited to Min and Max when set). The same Seed
+// generates the same payloads in the same VU, it's mixed with the VU id so
+// the VUs don't generate the same uuids.
+type PayloadOptions struct {
+	Template     string
+	Content      string
+	Distribution string
+	Size         int
+	Min          int
+	Max          int
+	Mean         float64
+	Stddev       float64
+	Seed         *uint64
+}
+
+// Payload generates a new message body each time it is sent.
+type Payload struct {
+	vu       modules.VU
+	opts     PayloadOptions
+	binary   bool
+	template []payloadSegment
+
+	seq atomic.Uint64
+	// mu guards rng, payloads are generated by producers out of the event loop
+	mu  sync.Mutex
+	rng *rand.Rand
+	// rngVU is the VU id mixed with the seed of rng
+	rngVU uint64
+}
+
+// payloadSegment is a literal text or a placeholder of a template.
+type payloadSegment struct {
+	text        string
+	placeholder string
+}
+
+// payloadEnv is the VU state used by the placeholders.
+type payloadEnv struct {
+	vu   uint64
+	iter int64
+}
+
+// Payload creates a payload generator to use as the body of sent messages.
+func (s *Stomp) Payload(opts *PayloadOptions) *Payload {
+	p, err := newPayload(s.vu, opts)
+	if err != nil {
+		common.Throw(s.vu.Runtime(), err)
+	}
+	return p
+}
+
+func newPayload(vu modules.VU, opts *PayloadOptions) (*Payload, error) {
+	if opts == nil {
+		return nil, errors.New("payload options are required")
+	}
+	p := Payload{vu: vu, opts: *opts}
+	switch p.opts.Content {
+	case "", payloadContentText:
+	case payloadContentBytes:
+		p.binary = true
+	default:
+		return nil, fmt.Errorf("payload content should be '%s' or '%s'", payloadContentText, payloadContentBytes)
+	}
+	switch p.opts.Distribution {
+	case "", payloadDistributionFixed:
+		if p.opts.Size < 0 {
+			return nil, errors.New("payload size should not be negative")
+		}
+	case payloadDistributionUniform:
+		if p.opts.Min < 0 || p.opts.Max < p.opts.Min {
+			return nil, errors.New("payload uniform distribution requires 0 <= min <= max")
+		}
+	case payloadDistributionNormal:
+		if p.opts.Mean < 0 || p.opts.Stddev < 0 {
+			return nil, errors.New("payload normal distribution requires a positive mean and stddev")
+		}
+	default:
+		return nil, fmt.Errorf("payload distribution should be '%s', '%s' or '%s'",
+			payloadDistributionFixed, payloadDistributionUniform, payloadDistributionNormal)
+	}
+	if p.opts.Template != "" {
+		template, err := parsePayloadTemplate(p.opts.Template)
+		if err != nil {
+			return nil, err
+		}
+		p.template = template
+	}
+
+	// seeded generators are seeded on the first payload, when the VU id is known
+	if p.opts.Seed == nil {
+		p.rng = rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	}
+	return &p, nil
+}
+
+func parsePayloadTemplate(template string) ([]payloadSegment, error) {
+	var segments []payloadSegment
+	for template != "" {
+		start := strings.Index(template, "{{")
+		if start < 0 {
+			segments = append(segments, payloadSegment{text: template})
+			break
+		}
+		end := strings.Index(template[start:], "}}")
+		if end < 0 {
+			return nil, fmt.Errorf("payload template: unclosed placeholder %q", template[start:])
+		}
+		name := strings.TrimSpace(template[start+2 : start+end])
+		if _, ok := payloadPlaceholders[name]; !ok {
+			return nil, fmt.Errorf("payload template: unknown placeholder %q", name)
+		}
+		if start > 0 {
+			segments = append(segments, payloadSegment{text: template[:start]})
+		}
+		segments = append(segments, payloadSegment{placeholder: name})
+		template = template[start+end+2:]
+	}
+	return segments, nil
+}
+
+// env returns the current VU state. It must be called on the event loop.
+func (p *Payload) env() payloadEnv {
+	var env payloadEnv
+	if state := p.vu.State(); state != nil {
+		env.vu, env.iter = state.VUID, state.Iteration
+	}
+	return env
+}
+
+// Next returns the next payload, a string or an ArrayBuffer for bytes content.
+func (p *Payload) Next() any {
+	body := p.generate(p.env())
+	if p.binary {
+		return p.vu.Runtime().NewArrayBuffer(body)
+	}
+	return string(body)
+}
+
+// generate returns a new payload.
+func (p *Payload) generate(env payloadEnv) []byte {
+	seq := p.seq.Add(1)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// generators created in the init context are seeded again in the VU
+	if p.opts.Seed != nil && (p.rng == nil || p.rngVU != env.vu) {
+		p.rng, p.rngVU = rand.New(rand.NewPCG(*p.opts.Seed, env.vu)), env.vu
+	}
+	if p.template == nil {
+		return p.random(nil)
+	}
+	var body []byte
+	for _, s := range p.template {
+		switch s.placeholder {
+		case "":
+			body = append(body, s.text...)
+		case "uuid":
+			var id uuid.UUID
+			for i := range id {
+				id[i] = byte(p.rng.UintN(256))
+			}
+			id[6] = (id[6] & 0x0f) | 0x40 // version 4
+			id[8] = (id[8] & 0x3f) | 0x80 // RFC 4122 variant
+			body = append(body, id.String()...)
+		case "seq":
+			body = strconv.AppendUint(body, seq, 10)
+		case "vu":
+			body = strconv.AppendUint(body, env.vu, 10)
+		case "iter":
+			body = strconv.AppendInt(body, env.iter, 10)
+		case "timestamp":
+			body = strconv.AppendInt(body, time.Now().UnixMilli(), 10)
+		case "random":
+			body = p.random(body)
+		}
+	}
+	return body
+}
+
+// random appends random content of the distribution size to body.
+func (p *Payload) random(body []byte) []byte {
+	size := p.size()
+	for range size {
+		if p.binary {
+			body = append(body, byte(p.rng.UintN(256)))
+		} else {
+			body = append(body, payloadTextChars[p.rng.IntN(len(payloadTextChars))])
+		}
+	}
+	return body
+}
+
+func (p *Payload) size() int {
+	switch p.opts.Distribution {
+	case payloadDistributionUniform:
+		return p.opts.Min + p.rng.IntN(p.opts.Max-p.opts.Min+1)
+	case payloadDistributionNormal:
+		size := int(math.Round(p.rng.NormFloat64()*p.opts.Stddev + p.opts.Mean))
+		if p.opts.Max > 0 {
+			size = min(size, p.opts.Max)
+		}
+		return max(size, p.opts.Min, 0)
+	default:
+		return p.opts.Size
+	}
+}
@@ -0,0 +1,288 @@
+package stomp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-stomp/stomp/v3"
+	"github.com/go-stomp/stomp/v3/frame"
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/metrics"
+)
+
+// producerTick is the interval the producer sends the messages due and reports them.
+const producerTick = 10 * time.Millisecond
+
+// ProducerOptions configures a producer sending Rate messages per second to
+// Destination until stopped or for Duration. The rate is increased linearly
+// from StartRate during RampUp.
+type ProducerOptions struct {
+	Destination string
+	Rate        float64
+	StartRate   float64
+	RampUp      string
+	Duration    string
+	Payload     sobek.Value
+	Headers     map[string]string
+	ContentType string
+}
+
+// Producer sends messages at a constant rate from a goroutine, off the event loop.
+type Producer struct {
+	client      *Client
+	destination string
+	contentType string
+	body        []byte
+	sendOpts    []func(*frame.Frame) error
+	// payload generates the body of each message, with the VU state of the start
+	payload *Payload
+	env     payloadEnv
+	headers SendOptions
+
+	rate      float64
+	startRate float64
+	rampUp    time.Duration
+	duration  time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	startedAt time.Time
+	sent      atomic.Int64
+	errors    atomic.Int64
+	// attempted and skipped are only used by the producing goroutine
+	attempted int64
+	skipped   int64
+
+	mu        sync.Mutex
+	stoppedAt time.Time
+	err       error
+}
+
+// StartProducer starts a producer. The iteration waits for the producer to
+// finish, after its duration or when stop() is called.
+func (c *Client) StartProducer(opts *ProducerOptions) *Producer {
+	c.events.dispatch()
+	rt := c.vu.Runtime()
+	conn := c.stompConn()
+	if conn == nil {
+		common.Throw(rt, ErrNotConnected)
+	}
+	if c.ctx.Err() != nil || c.vu.Context().Err() != nil || c.vu.State() == nil {
+		return nil
+	}
+	p, err := c.newProducer(opts)
+	if err != nil {
+		common.Throw(rt, err)
+	}
+	go p.run(c.vu.RegisterCallback(), conn)
+	return p
+}
+
+func (c *Client) newProducer(opts *ProducerOptions) (*Producer, error) {
+	if opts == nil || opts.Destination == "" {
+		return nil, errors.New("producer destination is required")
+	}
+	if opts.Rate <= 0 {
+		return nil, errors.New("producer rate should be greater than 0")
+	}
+	if opts.StartRate < 0 {
+		return nil, errors.New("producer start_rate should not be negative")
+	}
+	p := Producer{
+		client:      c,
+		destination: opts.Destination,
+		contentType: opts.ContentType,
+		rate:        opts.Rate,
+		startRate:   opts.StartRate,
+	}
+	var err error
+	if opts.RampUp != "" {
+		if p.rampUp, err = time.ParseDuration(opts.RampUp); err != nil {
+			return nil, fmt.Errorf("invalid producer ramp_up: %w", err)
+		}
+	}
+	if opts.Duration != "" {
+		if p.duration, err = time.ParseDuration(opts.Duration); err != nil {
+			return nil, fmt.Errorf("invalid producer duration: %w", err)
+		}
+	}
+	p.headers = SendOptions{Headers: opts.Headers}
+	if payload, ok := exportValue(opts.Payload).(*Payload); ok {
+		p.payload, p.env = payload, payload.env()
+		if payload.binary && p.contentType == "" {
+			p.contentType = binaryContentType
+		}
+	} else {
+		body, binary, err := messageBody(c.vu.Runtime(), opts.Payload)
+		if err == nil {
+			err = c.checkBody(body)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid producer payload: %w", err)
+		}
+		if binary && p.contentType == "" {
+			p.contentType = binaryContentType
+		}
+		p.body, p.sendOpts = body, p.headers.frameOptions(body, binary)
+	}
+
+	p.ctx, p.cancel = context.WithCancel(c.vu.Context())
+	p.startedAt = time.Now()
+	return &p, nil
+}
+
+// expected returns the number of messages due after elapsed.
+func (p *Producer) expected(elapsed time.Duration) int64 {
+	t := elapsed.Seconds()
+	if ramp := p.rampUp.Seconds(); ramp > 0 {
+		if t < ramp {
+			return int64(p.startRate*t + (p.rate-p.startRate)*t*t/(2*ramp))
+		}
+		return int64(p.startRate*ramp + (p.rate-p.startRate)*ramp/2 + p.rate*(t-ramp))
+	}
+	return int64(p.rate * t)
+}
+
+// run produces on conn and, when the connection is lost, reconnects without
+// blocking the event loop and restarts without sending the messages due meanwhile.
+func (p *Producer) run(runOnLoop func(func() error), conn *stomp.Conn) {
+	err := p.produce(conn)
+	if err == nil {
+		p.finish(nil)
+		runOnLoop(func() error { return nil })
+		return
+	}
+	runOnLoop(func() error {
+		if p.ctx.Err() != nil || !p.client.canRetry(err) {
+			p.finish(err)
+			return nil
+		}
+		return p.client.reconnectAsync(p.ctx, conn, func(reconnectErr error) error {
+			if reconnectErr != nil || p.ctx.Err() != nil {
+				p.finish(err)
+				return nil
+			}
+			p.skipped = p.expected(time.Since(p.startedAt)) - p.attempted
+			go p.run(p.client.vu.RegisterCallback(), p.client.stompConn())
+			return nil
+		})
+	})
+}
+
+// produce sends the messages due every tick. It returns an error when the connection is lost.
+func (p *Producer) produce(conn *stomp.Conn) error {
+	ticker := time.NewTicker(producerTick)
+	defer ticker.Stop()
+	tags := map[string]string{
+		METRIC_TAG_QUEUE: p.destination,
+	}
+	for {
+		elapsed := time.Since(p.startedAt)
+		if p.duration > 0 && elapsed >= p.duration {
+			elapsed = p.duration
+		}
+		var (
+			sent, failed int64
+			lost         error
+		)
+		startedAt := time.Now()
+		for due := p.expected(elapsed) - p.skipped - p.attempted; due > 0 && p.ctx.Err() == nil; due-- {
+			p.attempted++
+			body, sendOpts := p.body, p.sendOpts
+			if p.payload != nil {
+				body = p.payload.generate(p.env)
+				sendOpts = p.headers.frameOptions(body, p.payload.binary)
+				if p.client.checkBody(body) != nil {
+					failed++
+					continue
+				}
+			}
+			if err := conn.Send(p.destination, p.contentType, body, sendOpts...); err != nil {
+				failed++
+				if connectionLost(err) {
+					lost = err
+					break
+				}
+				continue
+			}
+			sent++
+		}
+		p.report(tags, sent, failed, time.Since(startedAt))
+		if lost != nil {
+			return lost
+		}
+		if p.duration > 0 && elapsed >= p.duration {
+			return nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-p.ctx.Done():
+			return nil
+		case <-p.client.ctx.Done():
+			return nil
+		}
+	}
+}
+
+// report adds the messages sent in a tick as one sample of each stomp_send_* metric.
+func (p *Producer) report(tags map[string]string, sent, failed int64, writeTime time.Duration) {
+	now := time.Now()
+	if sent > 0 {
+		p.sent.Add(sent)
+		p.client.reportStats(p.client.metrics.sendMessage, tags, now, float64(sent))
+		p.client.reportStats(p.client.metrics.sendMessageTiming, tags, now, metrics.D(writeTime/time.Duration(sent+failed)))
+	}
+	if failed > 0 {
+		p.errors.Add(failed)
+		p.client.reportStats(p.client.metrics.sendMessageErrors, tags, now, float64(failed))
+	}
+}
+
+// finish stops the producer after it ended, keeping the error that ended it.
+func (p *Producer) finish(err error) {
+	p.Stop()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+}
+
+// Stop stops the producer. The messages being sent are still reported.
+func (p *Producer) Stop() {
+	p.cancel()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stoppedAt.IsZero() {
+		p.stoppedAt = time.Now()
+	}
+}
+
+// Stats returns the messages sent and failed, the elapsed time and the actual rate.
+func (p *Producer) Stats() map[string]any {
+	p.mu.Lock()
+	stoppedAt, err := p.stoppedAt, p.err
+	p.mu.Unlock()
+	if stoppedAt.IsZero() {
+		stoppedAt = time.Now()
+	}
+	elapsed := stoppedAt.Sub(p.startedAt)
+	sent := p.sent.Load()
+
+	stats := map[string]any{
+		"sent":    sent,
+		"errors":  p.errors.Load(),
+		"elapsed": metrics.D(elapsed),
+		"rate":    float64(sent) / elapsed.Seconds(),
+		"running": p.ctx.Err() == nil,
+	}
+	if err != nil {
+		stats["error"] = err.Error()
+	}
+	return stats
+}
@@ -0,0 +1,154 @@
+package stomp
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// dialBroker opens a network connection to the broker address, through the
+// configured proxy if any.
+func (c *Client) dialBroker(ctx context.Context, network, addr string) (net.Conn, error) {
+	proxyURL, err := c.proxyURL(addr)
+	if err != nil {
+		return nil, err
+	}
+	if proxyURL == nil {
+		return c.dialContext(ctx, network, addr)
+	}
+
+	switch proxyURL.Scheme {
+	case "http", "https":
+		return c.dialHTTPProxy(ctx, proxyURL, network, addr)
+	case "socks5", "socks5h":
+		var auth *proxy.Auth
+		if proxyURL.User != nil {
+			auth = &proxy.Auth{User: proxyURL.User.Username()}
+			auth.Password, _ = proxyURL.User.Password()
+		}
+		dialer, err := proxy.SOCKS5(network, proxyURL.Host, auth, contextDialerFunc(c.dialContext))
+		if err != nil {
+			return nil, err
+		}
+		return dialer.(proxy.ContextDialer).DialContext(ctx, network, addr)
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", proxyURL.Scheme)
+	}
+}
+
+// proxyURL returns the proxy used to connect to addr. The environment variables
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY are used by ws, wss and sockjs connections
+// when proxy_from_environment is enabled.
+func (c *Client) proxyURL(addr string) (*url.URL, error) {
+	if c.opts.Proxy != "" {
+		u, err := url.Parse(c.opts.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy: %w", err)
+		}
+		return u, nil
+	}
+	if !c.opts.ProxyFromEnvironment {
+		return nil, nil
+	}
+	scheme := "http"
+	switch c.opts.Protocol {
+	case "wss":
+		scheme = "https"
+	case "ws":
+	case "sockjs":
+		if c.opts.TLS {
+			scheme = "https"
+		}
+	default:
+		return nil, nil
+	}
+	return http.ProxyFromEnvironment(&http.Request{URL: &url.URL{Scheme: scheme, Host: addr}})
+}
+
+// dialHTTPProxy opens a tunnel to addr with the HTTP CONNECT method.
+func (c *Client) dialHTTPProxy(ctx context.Context, proxyURL *url.URL, network, addr string) (net.Conn, error) {
+	proxyAddr := proxyURL.Host
+	if proxyURL.Port() == "" {
+		port := "80"
+		if proxyURL.Scheme == "https" {
+			port = "443"
+		}
+		proxyAddr = net.JoinHostPort(proxyURL.Hostname(), port)
+	}
+	conn, err := c.dialContext(ctx, network, proxyAddr)
+	if err != nil {
+		return nil, err
+	}
+	if proxyURL.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if proxyURL.User != nil {
+		pass, _ := proxyURL.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(proxyURL.User.Username() + ":" + pass))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy CONNECT %s: %s", addr, resp.Status)
+	}
+	if br.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, reader: br}, nil
+	}
+	return conn, nil
+}
+
+type contextDialerFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+func (f contextDialerFunc) Dial(network, addr string) (net.Conn, error) {
+	return f(context.Background(), network, addr)
+}
+
+func (f contextDialerFunc) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return f(ctx, network, addr)
+}
+
+// bufferedConn returns the bytes read ahead by the proxy response reader before
+// reading from the connection.
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (b *bufferedConn) Read(p []byte) (int, error) {
+	return b.reader.Read(p)
+}
@@ -0,0 +1,289 @@
+package stomp
+
+import (
+	"bytes"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-stomp/stomp/v3"
+	"github.com/go-stomp/stomp/v3/frame"
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/metrics"
+)
+
+// asyncReceiptHeader marks the SEND frames of sendAsync. go-stomp's Send blocks
+// the caller, the event loop, until the RECEIPT of a frame with a receipt
+// header arrives, so the header is renamed to receipt by the connection
+// monitor after go-stomp wrote the frame and the RECEIPT frames are matched
+// by the monitor.
+const asyncReceiptHeader = "xk6-stomp-receipt"
+
+var asyncReceiptID atomic.Uint64
+
+func newReceiptID() string {
+	return "async-" + strconv.FormatUint(asyncReceiptID.Add(1), 10)
+}
+
+// receiptTracker matches the RECEIPT frames of the messages sent by sendAsync.
+type receiptTracker struct {
+	mu      sync.Mutex
+	pending map[string]chan error
+	// err fails the receipts expected after the connection was closed
+	err error
+}
+
+// expect registers a receipt id. The channel receives nil when the RECEIPT
+// frame arrives or an error when the connection fails.
+func (r *receiptTracker) expect(id string) <-chan error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ch := make(chan error, 1)
+	if r.err != nil {
+		ch <- r.err
+		return ch
+	}
+	if r.pending == nil {
+		r.pending = make(map[string]chan error)
+	}
+	r.pending[id] = ch
+	return ch
+}
+
+func (r *receiptTracker) forget(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.pending, id)
+}
+
+func (r *receiptTracker) received(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if ch, ok := r.pending[id]; ok {
+		ch <- nil
+		delete(r.pending, id)
+	}
+}
+
+func (r *receiptTracker) fail(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, ch := range r.pending {
+		ch <- err
+		delete(r.pending, id)
+	}
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+// receiptWriter follows the frames written by go-stomp to rename the
+// asyncReceiptHeader of the SEND frames. go-stomp writes through a 4096 bytes
+// buffer, splitting larger frames across writes, so the command and headers
+// of each frame are held until complete while the bodies are passed through.
+type receiptWriter struct {
+	headers []byte
+	// inBody is set while passing a body through, remaining is the bytes left
+	// up to the null byte ending it, or -1 when it has no content-length.
+	inBody    bool
+	remaining int
+}
+
+// rewrite returns the bytes to write for p and the heart-beats written in it.
+func (r *receiptWriter) rewrite(p []byte) ([]byte, int) {
+	in := p
+	var (
+		out        []byte
+		heartbeats int
+		// changed is set once headers are held, until then p is written as is
+		changed bool
+	)
+	emit := func(b []byte) {
+		if changed {
+			out = append(out, b...)
+		}
+	}
+	for len(p) > 0 {
+		switch {
+		case r.inBody:
+			n := len(p)
+			if r.remaining >= 0 {
+				n = min(n, r.remaining)
+				r.remaining -= n
+				r.inBody = r.remaining > 0
+			} else if i := bytes.IndexByte(p, 0); i >= 0 {
+				n = i + 1
+				r.inBody = false
+			}
+			emit(p[:n])
+			p = p[n:]
+		case len(r.headers) == 0 && (p[0] == '\n' || p[0] == '\r'):
+			if p[0] == '\n' {
+				heartbeats++
+			}
+			emit(p[:1])
+			p = p[1:]
+		default:
+			if !changed {
+				out = append(out, in[:len(in)-len(p)]...)
+				changed = true
+			}
+			r.headers = append(r.headers, p...)
+			p = nil
+			headerEnd, contentLength := stompHeaders(r.headers)
+			if headerEnd == 0 {
+				break
+			}
+			headers, _ := requestReceipt(r.headers[:headerEnd])
+			out = append(out, headers...)
+			p = r.headers[headerEnd:]
+			r.headers = nil
+			r.inBody, r.remaining = true, contentLength
+			if contentLength >= 0 {
+				// the null byte ending the frame
+				r.remaining++
+			}
+		}
+	}
+	if !changed {
+		return in, heartbeats
+	}
+	return out, heartbeats
+}
+
+// requestReceipt renames the asyncReceiptHeader of a SEND frame to receipt.
+func requestReceipt(p []byte) ([]byte, bool) {
+	if !bytes.HasPrefix(p, []byte(frame.SEND+"\n")) {
+		return p, false
+	}
+	headers := p
+	if end := bytes.Index(p, []byte("\n\n")); end >= 0 {
+		headers = p[:end]
+	}
+	i := bytes.Index(headers, []byte("\n"+asyncReceiptHeader+":"))
+	if i < 0 {
+		return p, false
+	}
+	out := make([]byte, 0, len(p))
+	out = append(out, p[:i+1]...)
+	out = append(out, frame.Receipt...)
+	out = append(out, p[i+1+len(asyncReceiptHeader):]...)
+	return out, true
+}
+
+// receiptTimeout returns the time to wait for a RECEIPT frame.
+func (o *Options) receiptTimeout() time.Duration {
+	if o.ReceiptTimeout == "" {
+		return stomp.DefaultRcvReceiptTimeout
+	}
+	d, _ := time.ParseDuration(o.ReceiptTimeout)
+	return d
+}
+
+// waitReceipts waits for all the receipts until the receipt timeout.
+func (c *Client) waitReceipts(receipts ...<-chan error) error {
+	timer := time.NewTimer(c.opts.receiptTimeout())
+	defer timer.Stop()
+	for _, receipt := range receipts {
+		select {
+		case err := <-receipt:
+			if err != nil {
+				return err
+			}
+		case <-timer.C:
+			return stomp.ErrMsgReceiptTimeout
+		case <-c.ctx.Done():
+			return c.ctx.Err()
+		}
+	}
+	return nil
+}
+
+// SendAsync sends a message without blocking the event loop. It returns a Promise
+// resolved when the RECEIPT frame is received and rejected on ERROR or timeout,
+// so many confirmed messages can be in flight at the same time.
+func (c *Client) SendAsync(destination, contentType string, v sobek.Value, opts *SendOptions) *sobek.Promise {
+	c.events.dispatch()
+	rt := c.vu.Runtime()
+	promise, resolve, reject := rt.NewPromise()
+	if c.stompConn() == nil {
+		_ = reject(ErrNotConnected)
+		return promise
+	}
+	body, binary, err := messageBody(rt, v)
+	if err == nil {
+		err = c.checkBody(body)
+	}
+	if err != nil {
+		_ = reject(err)
+		return promise
+	}
+	if binary && contentType == "" {
+		contentType = binaryContentType
+	}
+	if opts == nil {
+		opts = new(SendOptions)
+	}
+	noReceipt := *opts
+	noReceipt.Receipt = false
+	sendOpts := noReceipt.frameOptions(body, binary)
+
+	c.sendAsync(destination, contentType, body, sendOpts, resolve, reject, true)
+	return promise
+}
+
+// sendAsync sends the message in a goroutine and settles the promise on the event loop.
+// It must be called on the event loop.
+func (c *Client) sendAsync(destination, contentType string, body []byte, sendOpts []func(*frame.Frame) error,
+	resolve, reject func(any) error, retry bool,
+) {
+	runOnLoop := c.vu.RegisterCallback()
+	c.mu.RLock()
+	conn, transport := c.conn, c.transport
+	c.mu.RUnlock()
+
+	go func() {
+		id := newReceiptID()
+		startedAt := time.Now()
+		receipt := transport.receipts.expect(id)
+		err := conn.Send(destination, contentType, body, append(sendOpts, stomp.SendOpt.Header(asyncReceiptHeader, id))...)
+		sentAt := time.Now()
+		if err == nil {
+			err = c.waitReceipts(receipt)
+		}
+		transport.receipts.forget(id)
+
+		now := time.Now()
+		tags := map[string]string{
+			METRIC_TAG_QUEUE: destination,
+		}
+		c.reportStats(c.metrics.sendMessageTiming, tags, now, metrics.D(sentAt.Sub(startedAt)))
+		if err == nil {
+			c.reportStats(c.metrics.sendMessage, tags, now, 1)
+			c.reportStats(c.metrics.receiptTiming, tags, now, metrics.D(now.Sub(startedAt)))
+			runOnLoop(func() error {
+				return resolve(map[string]any{
+					"destination":  destination,
+					"receipt_id":   id,
+					"receipt_time": metrics.D(now.Sub(startedAt)),
+				})
+			})
+			return
+		}
+		runOnLoop(func() error {
+			if !retry || !c.canRetry(err) {
+				c.reportStats(c.metrics.sendMessageErrors, tags, time.Now(), 1)
+				return reject(err)
+			}
+			return c.reconnectAsync(c.vu.Context(), conn, func(reconnectErr error) error {
+				if reconnectErr != nil {
+					c.reportStats(c.metrics.sendMessageErrors, tags, time.Now(), 1)
+					return reject(err)
+				}
+				c.sendAsync(destination, contentType, body, sendOpts, resolve, reject, false)
+				return nil
+			})
+		})
+	}()
+}
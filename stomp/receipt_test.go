@@ -0,0 +1,82 @@
+package stomp
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestReceiptWriterRewrite(t *testing.T) {
+	large := strings.Repeat("x", 5000)
+	bodyWithMarker := "\n" + asyncReceiptHeader + ":fake\x00\n"
+	tests := []struct {
+		name       string
+		in, want   string
+		heartbeats int
+	}{
+		{
+			name: "receipt",
+			in:   "SEND\ndestination:/q\n" + asyncReceiptHeader + ":async-1\n\nhello\x00",
+			want: "SEND\ndestination:/q\nreceipt:async-1\n\nhello\x00",
+		},
+		{
+			name: "headers larger than the write buffer",
+			in:   "SEND\ndestination:/q\nlarge:" + large + "\n" + asyncReceiptHeader + ":async-2\n\nhello\x00",
+			want: "SEND\ndestination:/q\nlarge:" + large + "\nreceipt:async-2\n\nhello\x00",
+		},
+		{
+			name: "body with content-length",
+			in: "SEND\ncontent-length:" + strconv.Itoa(len(bodyWithMarker)) + "\n" + asyncReceiptHeader + ":async-3\n\n" +
+				bodyWithMarker + "\x00",
+			want: "SEND\ncontent-length:" + strconv.Itoa(len(bodyWithMarker)) + "\nreceipt:async-3\n\n" +
+				bodyWithMarker + "\x00",
+		},
+		{
+			name: "without receipt",
+			in:   "SEND\ndestination:/q\n\n" + large + "\x00",
+			want: "SEND\ndestination:/q\n\n" + large + "\x00",
+		},
+		{
+			name: "other frames",
+			in:   "SUBSCRIBE\n" + asyncReceiptHeader + ":1\n\n\x00",
+			want: "SUBSCRIBE\n" + asyncReceiptHeader + ":1\n\n\x00",
+		},
+		{
+			name: "heart-beats between frames",
+			in:   "\nSEND\n" + asyncReceiptHeader + ":async-4\n\n\n\x00\n\r\n",
+			want: "\nSEND\nreceipt:async-4\n\n\n\x00\n\r\n",
+			// the EOL in the body is not a heart-beat
+			heartbeats: 3,
+		},
+	}
+	for _, tt := range tests {
+		for _, chunk := range []int{1, 7, 4096, len(tt.in)} {
+			var (
+				r          receiptWriter
+				out        []byte
+				heartbeats int
+			)
+			for in := []byte(tt.in); len(in) > 0; {
+				n := min(chunk, len(in))
+				b, h := r.rewrite(in[:n])
+				out = append(out, b...)
+				heartbeats += h
+				in = in[n:]
+			}
+			if !bytes.Equal(out, []byte(tt.want)) {
+				t.Errorf("%s, %d bytes writes: got %q, want %q", tt.name, chunk, truncate(out), truncate([]byte(tt.want)))
+			}
+			if heartbeats != tt.heartbeats {
+				t.Errorf("%s, %d bytes writes: got %d heart-beats, want %d", tt.name, chunk, heartbeats, tt.heartbeats)
+			}
+		}
+	}
+}
+
+func truncate(b []byte) []byte {
+	if len(b) > 200 {
+		return b[len(b)-200:]
+	}
+	return b
+}
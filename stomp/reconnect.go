@@ -0,0 +1,217 @@
+package stomp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/go-stomp/stomp/v3"
+	"github.com/go-stomp/stomp/v3/frame"
+	"go.k6.io/k6/metrics"
+)
+
+const (
+	defaultInitialBackoff = "500ms"
+	defaultMaxBackoff     = "30s"
+)
+
+// ReconnectOptions enables the automatic reconnection of a Client when the
+// connection is lost. Reconnection is disabled when MaxAttempts is zero and
+// unlimited when MaxAttempts is negative. Subscription listeners, sendAsync
+// and producers reconnect without blocking the event loop, while the
+// synchronous methods wait for the reconnection.
+type ReconnectOptions struct {
+	MaxAttempts    int
+	InitialBackoff string
+	MaxBackoff     string
+	// Jitter randomizes each backoff by up to the given fraction (0 to 1).
+	Jitter float64
+}
+
+type reconnectPolicy struct {
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	jitter         float64
+}
+
+func newReconnectPolicy(opts *ReconnectOptions) (*reconnectPolicy, error) {
+	if opts.MaxAttempts == 0 {
+		return nil, nil
+	}
+	if opts.InitialBackoff == "" {
+		opts.InitialBackoff = defaultInitialBackoff
+	}
+	if opts.MaxBackoff == "" {
+		opts.MaxBackoff = defaultMaxBackoff
+	}
+	if opts.Jitter < 0 || opts.Jitter > 1 {
+		return nil, fmt.Errorf("reconnect jitter should be between 0 and 1")
+	}
+	initialBackoff, err := time.ParseDuration(opts.InitialBackoff)
+	if err != nil {
+		return nil, err
+	}
+	maxBackoff, err := time.ParseDuration(opts.MaxBackoff)
+	if err != nil {
+		return nil, err
+	}
+	return &reconnectPolicy{
+		maxAttempts:    opts.MaxAttempts,
+		initialBackoff: initialBackoff,
+		maxBackoff:     maxBackoff,
+		jitter:         opts.Jitter,
+	}, nil
+}
+
+// backoff returns the time to wait after the given failed attempt (starting at 0).
+func (p *reconnectPolicy) backoff(attempt int) time.Duration {
+	d := p.maxBackoff
+	// compared shifting maxBackoff, initialBackoff<<attempt could overflow
+	if p.initialBackoff < p.maxBackoff>>attempt {
+		d = p.initialBackoff << attempt
+	}
+	if p.jitter > 0 {
+		d = time.Duration(float64(d) * (1 + p.jitter*(2*rand.Float64()-1)))
+	}
+	return d
+}
+
+// connectionLost reports whether err means the STOMP connection is no longer usable.
+// go-stomp closes the connection after any ERROR frame.
+func connectionLost(err error) bool {
+	if errors.Is(err, stomp.ErrAlreadyClosed) || errors.Is(err, stomp.ErrClosedUnexpectedly) {
+		return true
+	}
+	var stompErr stomp.Error
+	return errors.As(err, &stompErr) && stompErr.Frame != nil && stompErr.Frame.Command == frame.ERROR
+}
+
+func (c *Client) canReconnect() bool {
+	return c.reconnectPolicy != nil && c.ctx.Err() == nil
+}
+
+// canRetry reports whether the operation failed with err can be retried after a reconnect.
+func (c *Client) canRetry(err error) bool {
+	return c.canReconnect() && connectionLost(err)
+}
+
+// tryReconnect replaces conn when err means it was lost and reports whether
+// the operation can be retried. It must be called on the event loop and
+// blocks it until reconnected, so it's only used by the synchronous methods.
+func (c *Client) tryReconnect(conn *stomp.Conn, err error) bool {
+	if !c.canRetry(err) {
+		return false
+	}
+	return c.reconnectOnLoop(conn) == nil
+}
+
+// reconnectOnLoop calls the token provider before replacing failed,
+// so it must be called on the event loop.
+func (c *Client) reconnectOnLoop(failed *stomp.Conn) error {
+	if c.stompConn() == failed {
+		if err := c.refreshToken(); err != nil {
+			return err
+		}
+	}
+	return c.reconnect(c.vu.Context(), failed)
+}
+
+// reconnectAsync replaces failed without blocking the event loop: the token
+// provider is called on the loop, then the connection is dialed in a goroutine
+// and done is called back on the loop with the result. It must be called on
+// the event loop.
+func (c *Client) reconnectAsync(ctx context.Context, failed *stomp.Conn, done func(error) error) error {
+	if c.stompConn() == failed {
+		if err := c.refreshToken(); err != nil {
+			return done(err)
+		}
+	}
+	runOnLoop := c.vu.RegisterCallback()
+	go func() {
+		err := c.reconnect(ctx, failed)
+		runOnLoop(func() error {
+			return done(err)
+		})
+	}()
+	return nil
+}
+
+// reconnect dials a new connection replacing failed and restores the active
+// subscriptions. It is a no-op if failed was already replaced. The backoff
+// is interrupted when ctx or the client context is done.
+func (c *Client) reconnect(ctx context.Context, failed *stomp.Conn) error {
+	c.reconnectMu.Lock()
+	defer c.reconnectMu.Unlock()
+	if c.stompConn() != failed {
+		return nil
+	}
+
+	startedAt := time.Now()
+	var (
+		established *connection
+		err         error
+		attempts    int
+	)
+	for attempt := 0; c.reconnectPolicy.maxAttempts < 0 || attempt < c.reconnectPolicy.maxAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(c.reconnectPolicy.backoff(attempt - 1)):
+			case <-c.ctx.Done():
+				return c.ctx.Err()
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		attempts++
+		if established, err = c.dial(); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("reconnect failed: %w", err)
+	}
+	_ = failed.MustDisconnect()
+
+	c.mu.Lock()
+	c.setConnection(established)
+	subscriptions := make([]*Subscription, 0, len(c.subscriptions))
+	for s := range c.subscriptions {
+		subscriptions = append(subscriptions, s)
+	}
+	c.mu.Unlock()
+	// restored without holding mu: SUBSCRIBE frames are written by go-stomp
+	// and block when its write channel is full
+	for _, s := range subscriptions {
+		if err = s.restore(established.conn); err != nil {
+			break
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("restoring subscriptions: %w", err)
+	}
+
+	now := time.Now()
+	c.reportStats(c.metrics.reconnect, nil, now, 1)
+	c.reportStats(c.metrics.reconnectTiming, nil, now, metrics.D(now.Sub(startedAt)))
+	c.events.emit(eventReconnect, map[string]any{
+		"node":     established.node,
+		"attempts": attempts,
+		"duration": now.Sub(startedAt).String(),
+	})
+	return nil
+}
+
+func (c *Client) track(s *Subscription) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.subscriptions[s] = struct{}{}
+}
+
+func (c *Client) untrack(s *Subscription) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.subscriptions, s)
+}
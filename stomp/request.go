@@ -0,0 +1,244 @@
+package stomp
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-stomp/stomp/v3"
+	"github.com/go-stomp/stomp/v3/frame"
+	"github.com/google/uuid"
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/metrics"
+)
+
+const (
+	defaultRequestTimeout = "30s"
+	tempQueuePrefix       = "/temp-queue/xk6-stomp-"
+	correlationIDHeader   = "correlation-id"
+	replyToHeader         = "reply-to"
+)
+
+// ErrRequestTimeout is returned when no reply is received before the request timeout.
+var ErrRequestTimeout = errors.New("request: no reply received before timeout")
+
+// RequestOptions configures a request. ReplyTo is the destination the
+// replies are sent to, a temporary queue of the client by default.
+type RequestOptions struct {
+	ReplyTo     string
+	Timeout     string
+	Headers     map[string]string
+	ContentType string
+}
+
+// replyQueue routes the replies received on a reply destination to the
+// requests waiting for them by correlation-id.
+type replyQueue struct {
+	sub *Subscription
+
+	mu      sync.Mutex
+	pending map[string]chan *stomp.Message
+	// routing is the go-stomp subscription read by the route goroutine
+	routing *stomp.Subscription
+}
+
+// start routes the replies of the current subscription, which is replaced on reconnect.
+func (q *replyQueue) start() {
+	sc, _ := q.sub.current()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.routing != sc {
+		q.routing = sc
+		go q.route(sc)
+	}
+}
+
+func (q *replyQueue) route(sc *stomp.Subscription) {
+	for msg := range sc.C {
+		if msg.Err != nil {
+			q.fail(sc, msg.Err)
+			continue
+		}
+		// replies without a waiting request, like the late ones, are dropped
+		q.mu.Lock()
+		id := msg.Header.Get(correlationIDHeader)
+		if ch, ok := q.pending[id]; ok {
+			ch <- msg
+			delete(q.pending, id)
+		}
+		q.mu.Unlock()
+	}
+	q.fail(sc, stomp.ErrClosedUnexpectedly)
+}
+
+// fail wakes up the pending requests with err, unless sc was already
+// replaced by a reconnect.
+func (q *replyQueue) fail(sc *stomp.Subscription, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.routing != sc {
+		return
+	}
+	for id, ch := range q.pending {
+		ch <- &stomp.Message{Err: err}
+		delete(q.pending, id)
+	}
+}
+
+func (q *replyQueue) expect(id string) <-chan *stomp.Message {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	ch := make(chan *stomp.Message, 1)
+	q.pending[id] = ch
+	return ch
+}
+
+func (q *replyQueue) forget(id string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.pending, id)
+}
+
+// replyQueue returns the queue routing the replies sent to destination,
+// subscribing to it on the first request.
+func (c *Client) replyQueue(destination string) (*replyQueue, error) {
+	if q, ok := c.replies[destination]; ok && q.sub.Active() {
+		return q, nil
+	}
+	// the replies are routed by go-stomp from the subscription header, the
+	// subscription id is the destination, as RabbitMQ sets it for temp queues
+	subOpts := []func(*frame.Frame) error{stomp.SubscribeOpt.Id(destination)}
+	if destination == c.tempQueue {
+		// RabbitMQ creates the temp queue from the reply-to header of the request,
+		// go-stomp routes its replies without sending SUBSCRIBE
+		subOpts = []func(*frame.Frame) error{stomp.SubscribeOpt.Header(stomp.ReplyToHeader, destination)}
+	}
+	conn := c.stompConn()
+	sub, err := conn.Subscribe(destination, stomp.AckAuto, subOpts...)
+	if err != nil && c.tryReconnect(conn, err) {
+		conn = c.stompConn()
+		sub, err = conn.Subscribe(destination, stomp.AckAuto, subOpts...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("subscribing to reply destination: %w", err)
+	}
+	q := replyQueue{
+		sub:     NewSubscription(c, conn, sub, subOpts, nil, nil),
+		pending: make(map[string]chan *stomp.Message),
+	}
+	if c.replies == nil {
+		c.replies = make(map[string]*replyQueue)
+	}
+	c.replies[destination] = &q
+	return &q, nil
+}
+
+// Request sends a message with reply-to and correlation-id headers and
+// waits for the reply with the same correlation-id. A lost connection is
+// reconnected once, sending the request again on the new one.
+func (c *Client) Request(destination string, v sobek.Value, opts *RequestOptions) (reply *Message, err error) {
+	c.events.dispatch()
+	rt := c.vu.Runtime()
+	if c.stompConn() == nil {
+		common.Throw(rt, ErrNotConnected)
+	}
+	if c.ctx.Err() != nil || c.vu.Context().Err() != nil || c.vu.State() == nil {
+		return nil, nil
+	}
+	if opts == nil {
+		opts = new(RequestOptions)
+	}
+	if opts.Timeout == "" {
+		opts.Timeout = defaultRequestTimeout
+	}
+	timeout, err := time.ParseDuration(opts.Timeout)
+	if err != nil {
+		common.Throw(rt, fmt.Errorf("invalid request timeout: %w", err))
+	}
+	if opts.ReplyTo == "" {
+		if c.tempQueue == "" {
+			c.tempQueue = tempQueuePrefix + uuid.NewString()
+		}
+		opts.ReplyTo = c.tempQueue
+	}
+	body, binary, err := messageBody(rt, v)
+	if err == nil {
+		err = c.checkBody(body)
+	}
+	if err != nil {
+		common.Throw(rt, err)
+	}
+	contentType := opts.ContentType
+	if binary && contentType == "" {
+		contentType = binaryContentType
+	}
+
+	startedAt := time.Now()
+	defer func() {
+		now := time.Now()
+		tags := map[string]string{
+			METRIC_TAG_QUEUE: destination,
+		}
+		switch {
+		case err == nil:
+			c.reportStats(c.metrics.requestTiming, tags, now, metrics.D(now.Sub(startedAt)))
+		case errors.Is(err, ErrRequestTimeout):
+			c.reportStats(c.metrics.requestTimeouts, tags, now, 1)
+		}
+	}()
+
+	var msg *stomp.Message
+	for retried := false; ; retried = true {
+		var conn *stomp.Conn
+		conn, msg, err = c.request(destination, contentType, body, binary, timeout, opts)
+		if err == nil || retried || !c.tryReconnect(conn, err) {
+			break
+		}
+	}
+	if err != nil {
+		common.Throw(rt, err)
+	}
+	return &Message{Message: msg, vu: c.vu}, nil
+}
+
+// request sends the message and waits for the reply. It returns the
+// connection used, so a lost one can be reconnected.
+func (c *Client) request(destination, contentType string, body []byte, binary bool, timeout time.Duration,
+	opts *RequestOptions,
+) (*stomp.Conn, *stomp.Message, error) {
+	conn := c.stompConn()
+	q, err := c.replyQueue(opts.ReplyTo)
+	if err != nil {
+		return conn, nil, err
+	}
+	q.start()
+	conn = c.stompConn()
+
+	id := uuid.NewString()
+	reply := q.expect(id)
+	defer q.forget(id)
+
+	sendOpts := SendOptions{Headers: opts.Headers}
+	frameOpts := append(sendOpts.frameOptions(body, binary),
+		stomp.SendOpt.Header(replyToHeader, opts.ReplyTo),
+		stomp.SendOpt.Header(correlationIDHeader, id),
+	)
+	if err := conn.Send(destination, contentType, body, frameOpts...); err != nil {
+		return conn, nil, err
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case msg := <-reply:
+		return conn, msg, msg.Err
+	case <-timer.C:
+		return conn, nil, ErrRequestTimeout
+	case <-c.ctx.Done():
+		return conn, nil, c.ctx.Err()
+	case <-c.vu.Context().Done():
+		return conn, nil, c.vu.Context().Err()
+	}
+}
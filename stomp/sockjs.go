@@ -0,0 +1,385 @@
+package stomp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	sockJSTransportWebSocket     = "websocket"
+	sockJSTransportXHRStreaming  = "xhr-streaming"
+	sockJSSessionIDLength        = 16
+	sockJSSessionIDChars         = "abcdefghijklmnopqrstuvwxyz0123456789"
+	sockJSMaxServerID            = 1000
+	sockJSStreamingMaxFrameBytes = 1 << 20
+)
+
+// ErrSockJSBinary is returned when sending a body that isn't valid UTF-8
+// text over the sockjs protocol, which only carries strings.
+var ErrSockJSBinary = errors.New("sockjs: only UTF-8 text bodies can be sent")
+
+// checkBody returns ErrSockJSBinary when body can't be sent over the
+// connection, before go-stomp writes it and fails the whole connection.
+func (c *Client) checkBody(body []byte) error {
+	if c.opts.Protocol == "sockjs" && !utf8.Valid(body) {
+		return ErrSockJSBinary
+	}
+	return nil
+}
+
+// sockJSTransport exchanges raw SockJS frames with the server.
+type sockJSTransport interface {
+	readFrame() ([]byte, error)
+	send(payload []byte) error
+	Close() error
+}
+
+// sockJSConn is a SockJS session carrying STOMP frames. Received "a[...]"
+// frames are unwrapped into a buffer and "h" heartbeats are skipped. SockJS
+// messages are JSON strings, so only text frames can be sent.
+type sockJSConn struct {
+	transport sockJSTransport
+	buf       []byte
+	// pending holds the written bytes until they complete a STOMP frame
+	pending []byte
+}
+
+type sockJSInfo struct {
+	WebSocket bool `json:"websocket"`
+}
+
+func openSockJSConn(ctx context.Context, opts *Options, addr string, c *Client) (*sockJSConn, error) {
+	scheme := "http"
+	if opts.TLS {
+		scheme = "https"
+	}
+	base := url.URL{Scheme: scheme, Host: addr, Path: strings.TrimSuffix(opts.Path, "/"), RawQuery: opts.Query}
+
+	tlsConfig, err := newTLSConfig(opts, c.vu.State())
+	if err != nil {
+		return nil, err
+	}
+	httpClient := &http.Client{
+		Transport: &http.Transport{
+			DialContext: c.dialBroker,
+			DialTLSContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				conn, err := c.dialBroker(ctx, network, addr)
+				if err != nil {
+					return nil, err
+				}
+				return tlsHandshake(ctx, conn, tlsConfig, addr)
+			},
+		},
+	}
+
+	// only xhr-streaming keeps using the HTTP connections after the session is open
+	var conn sockJSConn
+	defer func() {
+		if _, ok := conn.transport.(*sockJSXHRStreaming); !ok {
+			httpClient.CloseIdleConnections()
+		}
+	}()
+
+	transport := opts.SockJSTransport
+	if transport == "" {
+		info, err := sockJSGetInfo(ctx, httpClient, base, c.httpHeaders(opts))
+		if err != nil {
+			connectTraceFrom(ctx).fail(connectPhaseSockJS)
+			return nil, err
+		}
+		transport = sockJSTransportXHRStreaming
+		if info.WebSocket {
+			transport = sockJSTransportWebSocket
+		}
+	}
+
+	session := base
+	session.Path = fmt.Sprintf("%s/%03d/%s", base.Path, rand.IntN(sockJSMaxServerID), sockJSSessionID())
+
+	switch transport {
+	case sockJSTransportWebSocket:
+		if opts.TLS {
+			session.Scheme = "wss"
+		} else {
+			session.Scheme = "ws"
+		}
+		session.Path += "/websocket"
+		ws, err := dialWS(ctx, opts, session.String(), nil, c)
+		if err != nil {
+			return nil, err
+		}
+		conn.transport = &sockJSWebSocket{ws}
+	case sockJSTransportXHRStreaming:
+		xhr, err := openSockJSXHRStreaming(ctx, httpClient, session, opts, c)
+		if err != nil {
+			connectTraceFrom(ctx).fail(connectPhaseSockJS)
+			return nil, err
+		}
+		conn.transport = xhr
+	default:
+		return nil, fmt.Errorf("sockjs_transport should be '%s' or '%s'", sockJSTransportWebSocket, sockJSTransportXHRStreaming)
+	}
+
+	if err := conn.waitOpen(); err != nil {
+		connectTraceFrom(ctx).fail(connectPhaseSockJS)
+		conn.Close()
+		return nil, err
+	}
+	return &conn, nil
+}
+
+func sockJSGetInfo(ctx context.Context, httpClient *http.Client, base url.URL, headers http.Header) (*sockJSInfo, error) {
+	base.Path += "/info"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = headers
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("sockjs info: unexpected status %d", resp.StatusCode)
+	}
+	var info sockJSInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, fmt.Errorf("sockjs info: %w", err)
+	}
+	return &info, nil
+}
+
+func sockJSSessionID() string {
+	id := make([]byte, sockJSSessionIDLength)
+	for i := range id {
+		id[i] = sockJSSessionIDChars[rand.IntN(len(sockJSSessionIDChars))]
+	}
+	return string(id)
+}
+
+// waitOpen reads the frames preceding the "o" open frame.
+func (s *sockJSConn) waitOpen() error {
+	for {
+		frame, err := s.transport.readFrame()
+		if err != nil {
+			return err
+		}
+		switch {
+		case len(frame) == 0 || frame[0] == 'h':
+			continue
+		case frame[0] == 'o':
+			return nil
+		case frame[0] == 'c':
+			return fmt.Errorf("sockjs session closed: %s", frame[1:])
+		default:
+			return fmt.Errorf("sockjs: unexpected frame before open: %q", frame)
+		}
+	}
+}
+
+func (s *sockJSConn) Read(p []byte) (int, error) {
+	for len(s.buf) == 0 {
+		frame, err := s.transport.readFrame()
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case len(frame) == 0 || frame[0] == 'h' || frame[0] == 'o':
+			continue
+		case frame[0] == 'a':
+			var messages []string
+			if err := json.Unmarshal(frame[1:], &messages); err != nil {
+				return 0, fmt.Errorf("sockjs: invalid frame: %w", err)
+			}
+			for _, message := range messages {
+				s.buf = append(s.buf, message...)
+			}
+		case frame[0] == 'c':
+			return 0, fmt.Errorf("sockjs session closed: %s: %w", frame[1:], io.EOF)
+		default:
+			return 0, fmt.Errorf("sockjs: unexpected frame: %q", frame)
+		}
+	}
+	n := copy(p, s.buf)
+	s.buf = s.buf[n:]
+	return n, nil
+}
+
+// Write sends each complete STOMP frame as a SockJS message, as go-stomp may
+// split a frame across writes, even in the middle of a multibyte character.
+func (s *sockJSConn) Write(p []byte) (int, error) {
+	s.pending = append(s.pending, p...)
+	for {
+		n := stompFrameLen(s.pending)
+		if n == 0 {
+			break
+		}
+		if !utf8.Valid(s.pending[:n]) {
+			s.pending = nil
+			return 0, ErrSockJSBinary
+		}
+		payload, err := json.Marshal([]string{string(s.pending[:n])})
+		if err != nil {
+			return 0, err
+		}
+		if err := s.transport.send(payload); err != nil {
+			return 0, err
+		}
+		s.pending = s.pending[n:]
+	}
+	if len(s.pending) == 0 {
+		s.pending = nil
+	}
+	return len(p), nil
+}
+
+func (s *sockJSConn) Close() error {
+	return s.transport.Close()
+}
+
+type sockJSWebSocket struct {
+	conn *websocket.Conn
+}
+
+func (w *sockJSWebSocket) readFrame() ([]byte, error) {
+	_, m, err := w.conn.ReadMessage()
+	return m, err
+}
+
+func (w *sockJSWebSocket) send(payload []byte) error {
+	return w.conn.WriteMessage(websocket.TextMessage, payload)
+}
+
+func (w *sockJSWebSocket) Close() error {
+	return w.conn.Close()
+}
+
+// sockJSXHRStreaming receives frames from a long lived xhr_streaming response,
+// one per line, and sends messages with xhr_send requests.
+type sockJSXHRStreaming struct {
+	ctx         context.Context
+	cancel      context.CancelFunc
+	httpClient  *http.Client
+	sendURL     string
+	sendTimeout time.Duration
+	headers     http.Header
+	body        io.ReadCloser
+	reader      *bufio.Reader
+}
+
+func openSockJSXHRStreaming(ctx context.Context, httpClient *http.Client, session url.URL, opts *Options, c *Client) (*sockJSXHRStreaming, error) {
+	sendTimeout, err := time.ParseDuration(opts.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	xhr := sockJSXHRStreaming{
+		httpClient:  httpClient,
+		sendTimeout: sendTimeout,
+		headers:     c.httpHeaders(opts),
+	}
+	// the streaming response and the sends live until the transport is closed,
+	// not the connect timeout nor the client context, done before DISCONNECT is sent
+	xhr.ctx, xhr.cancel = context.WithCancel(context.Background())
+
+	sendURL := session
+	sendURL.Path += "/xhr_send"
+	xhr.sendURL = sendURL.String()
+
+	session.Path += "/xhr_streaming"
+	req, err := http.NewRequestWithContext(xhr.ctx, http.MethodPost, session.String(), nil)
+	if err != nil {
+		xhr.cancel()
+		return nil, err
+	}
+	req.Header = xhr.headers.Clone()
+
+	type result struct {
+		resp *http.Response
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		resp, err := httpClient.Do(req)
+		done <- result{resp, err}
+	}()
+	var res result
+	select {
+	case res = <-done:
+	case <-ctx.Done():
+		xhr.cancel()
+		return nil, ctx.Err()
+	}
+	if res.err != nil {
+		xhr.cancel()
+		return nil, res.err
+	}
+	if res.resp.StatusCode != http.StatusOK {
+		res.resp.Body.Close()
+		xhr.cancel()
+		return nil, fmt.Errorf("sockjs xhr_streaming: unexpected status %d", res.resp.StatusCode)
+	}
+	xhr.body = res.resp.Body
+	xhr.reader = bufio.NewReader(res.resp.Body)
+	return &xhr, nil
+}
+
+func (x *sockJSXHRStreaming) readFrame() ([]byte, error) {
+	var frame []byte
+	for {
+		line, isPrefix, err := x.reader.ReadLine()
+		if err != nil {
+			return nil, err
+		}
+		frame = append(frame, line...)
+		if len(frame) > sockJSStreamingMaxFrameBytes {
+			return nil, errors.New("sockjs xhr_streaming: frame too large")
+		}
+		if !isPrefix {
+			return frame, nil
+		}
+	}
+}
+
+func (x *sockJSXHRStreaming) send(payload []byte) error {
+	ctx, cancel := context.WithTimeout(x.ctx, x.sendTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, x.sendURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header = x.headers.Clone()
+	req.Header.Set("Content-Type", "text/plain;charset=UTF-8")
+	resp, err := x.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("sockjs xhr_send: unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (x *sockJSXHRStreaming) Close() error {
+	x.cancel()
+	err := x.body.Close()
+	// the kept-alive xhr_send connections are owned by the session transport
+	x.httpClient.CloseIdleConnections()
+	return err
+}
@@ -3,6 +3,7 @@ package stomp
 import (
 	"errors"
 	"io"
+	"net"
 	"time"
 
 	"go.k6.io/k6/js/modules"
@@ -26,6 +27,35 @@ type stompMetrics struct {
 
 	nackMessage       *metrics.Metric
 	nackMessageErrors *metrics.Metric
+
+	reconnect       *metrics.Metric
+	reconnectTiming *metrics.Metric
+
+	wsWireDataSent     *metrics.Metric
+	wsWireDataReceived *metrics.Metric
+
+	dnsLookup      *metrics.Metric
+	connecting     *metrics.Metric
+	tlsHandshaking *metrics.Metric
+	wsUpgrading    *metrics.Metric
+	connectTiming  *metrics.Metric
+	connectErrors  *metrics.Metric
+
+	heartbeatSent     *metrics.Metric
+	heartbeatReceived *metrics.Metric
+	heartbeatInterval *metrics.Metric
+	heartbeatMissed   *metrics.Metric
+
+	leakedConnections *metrics.Metric
+
+	disconnectTiming *metrics.Metric
+	disconnectErrors *metrics.Metric
+
+	receiptTiming *metrics.Metric
+	batchTiming   *metrics.Metric
+
+	requestTiming   *metrics.Metric
+	requestTimeouts *metrics.Metric
 }
 
 func registerMetrics(vu modules.VU) (stompMetrics, error) {
@@ -81,9 +111,119 @@ func registerMetrics(vu modules.VU) (stompMetrics, error) {
 		return sm, errors.Unwrap(err)
 	}
 
+	if sm.reconnect, err = registry.NewMetric("stomp_reconnect_count", metrics.Counter); err != nil {
+		return sm, errors.Unwrap(err)
+	}
+
+	if sm.reconnectTiming, err = registry.NewMetric("stomp_reconnect_time", metrics.Trend, metrics.Time); err != nil {
+		return sm, errors.Unwrap(err)
+	}
+
+	if sm.wsWireDataSent, err = registry.NewMetric("stomp_ws_wire_data_sent", metrics.Counter, metrics.Data); err != nil {
+		return sm, errors.Unwrap(err)
+	}
+
+	if sm.wsWireDataReceived, err = registry.NewMetric("stomp_ws_wire_data_received", metrics.Counter, metrics.Data); err != nil {
+		return sm, errors.Unwrap(err)
+	}
+
+	if sm.dnsLookup, err = registry.NewMetric("stomp_dns_lookup", metrics.Trend, metrics.Time); err != nil {
+		return sm, errors.Unwrap(err)
+	}
+
+	if sm.connecting, err = registry.NewMetric("stomp_connecting", metrics.Trend, metrics.Time); err != nil {
+		return sm, errors.Unwrap(err)
+	}
+
+	if sm.tlsHandshaking, err = registry.NewMetric("stomp_tls_handshaking", metrics.Trend, metrics.Time); err != nil {
+		return sm, errors.Unwrap(err)
+	}
+
+	if sm.wsUpgrading, err = registry.NewMetric("stomp_ws_upgrading", metrics.Trend, metrics.Time); err != nil {
+		return sm, errors.Unwrap(err)
+	}
+
+	if sm.connectTiming, err = registry.NewMetric("stomp_connect_time", metrics.Trend, metrics.Time); err != nil {
+		return sm, errors.Unwrap(err)
+	}
+
+	if sm.connectErrors, err = registry.NewMetric("stomp_connect_error_count", metrics.Counter); err != nil {
+		return sm, errors.Unwrap(err)
+	}
+
+	if sm.heartbeatSent, err = registry.NewMetric("stomp_heartbeat_sent_count", metrics.Counter); err != nil {
+		return sm, errors.Unwrap(err)
+	}
+
+	if sm.heartbeatReceived, err = registry.NewMetric("stomp_heartbeat_received_count", metrics.Counter); err != nil {
+		return sm, errors.Unwrap(err)
+	}
+
+	if sm.heartbeatInterval, err = registry.NewMetric("stomp_heartbeat_interval", metrics.Trend, metrics.Time); err != nil {
+		return sm, errors.Unwrap(err)
+	}
+
+	if sm.heartbeatMissed, err = registry.NewMetric("stomp_heartbeat_missed_count", metrics.Counter); err != nil {
+		return sm, errors.Unwrap(err)
+	}
+
+	if sm.leakedConnections, err = registry.NewMetric("stomp_leaked_connections", metrics.Counter); err != nil {
+		return sm, errors.Unwrap(err)
+	}
+
+	if sm.disconnectTiming, err = registry.NewMetric("stomp_disconnect_time", metrics.Trend, metrics.Time); err != nil {
+		return sm, errors.Unwrap(err)
+	}
+
+	if sm.disconnectErrors, err = registry.NewMetric("stomp_disconnect_error_count", metrics.Counter); err != nil {
+		return sm, errors.Unwrap(err)
+	}
+
+	if sm.receiptTiming, err = registry.NewMetric("stomp_receipt_time", metrics.Trend, metrics.Time); err != nil {
+		return sm, errors.Unwrap(err)
+	}
+
+	if sm.batchTiming, err = registry.NewMetric("stomp_batch_time", metrics.Trend, metrics.Time); err != nil {
+		return sm, errors.Unwrap(err)
+	}
+
+	if sm.requestTiming, err = registry.NewMetric("stomp_request_time", metrics.Trend, metrics.Time); err != nil {
+		return sm, errors.Unwrap(err)
+	}
+
+	if sm.requestTimeouts, err = registry.NewMetric("stomp_request_timeout_count", metrics.Counter); err != nil {
+		return sm, errors.Unwrap(err)
+	}
+
 	return sm, nil
 }
 
+// reportConnectStats reports the phases of a connection attempt to addr.
+func (c *Client) reportConnectStats(addr string, trace *connectTrace, err error) {
+	now := time.Now()
+	tags := map[string]string{
+		METRIC_TAG_NODE: addr,
+	}
+	if trace.dnsLookup > 0 {
+		c.reportStats(c.metrics.dnsLookup, tags, now, metrics.D(trace.dnsLookup))
+	}
+	if trace.connecting > 0 {
+		c.reportStats(c.metrics.connecting, tags, now, metrics.D(trace.connecting))
+	}
+	if trace.tlsHandshaking > 0 {
+		c.reportStats(c.metrics.tlsHandshaking, tags, now, metrics.D(trace.tlsHandshaking))
+	}
+	if trace.wsUpgrading > 0 {
+		c.reportStats(c.metrics.wsUpgrading, tags, now, metrics.D(trace.wsUpgrading))
+	}
+	if err != nil {
+		tags[METRIC_TAG_PHASE] = trace.failedPhase
+		c.reportStats(c.metrics.connectErrors, tags, now, 1)
+		return
+	}
+	c.reportStats(c.metrics.connectTiming, tags, now, metrics.D(trace.stompConnect))
+}
+
 type StatsReadWriteClose struct {
 	io.ReadWriteCloser
 	client *Client
@@ -100,3 +240,21 @@ func (s *StatsReadWriteClose) Write(p []byte) (int, error) {
 	s.client.reportStats(s.client.metrics.dataSent, nil, time.Now(), float64(n))
 	return n, err
 }
+
+// wireStatsConn reports the bytes exchanged on the network by compressed WebSocket connections.
+type wireStatsConn struct {
+	net.Conn
+	client *Client
+}
+
+func (w *wireStatsConn) Read(p []byte) (int, error) {
+	n, err := w.Conn.Read(p)
+	w.client.reportStats(w.client.metrics.wsWireDataReceived, nil, time.Now(), float64(n))
+	return n, err
+}
+
+func (w *wireStatsConn) Write(p []byte) (int, error) {
+	n, err := w.Conn.Write(p)
+	w.client.reportStats(w.client.metrics.wsWireDataSent, nil, time.Now(), float64(n))
+	return n, err
+}
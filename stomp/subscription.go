@@ -1,6 +1,7 @@
 package stomp
 
 import (
+	"sync"
 	"time"
 
 	"github.com/go-stomp/stomp/v3"
@@ -16,16 +17,25 @@ type Subscription struct {
 	listener      Listener
 	listenerError ListenerError
 	done          chan bool
+
+	// mu guards the embedded subscription and conn, which are replaced on reconnect.
+	mu           sync.RWMutex
+	conn         *stomp.Conn
+	subOpts      []func(*frame.Frame) error
+	unsubscribed bool
 }
 
-func NewSubscription(client *Client, sc *stomp.Subscription, listener Listener, listenerError ListenerError) *Subscription {
+func NewSubscription(client *Client, conn *stomp.Conn, sc *stomp.Subscription, subOpts []func(*frame.Frame) error, listener Listener, listenerError ListenerError) *Subscription {
 	s := Subscription{
 		client:        client,
 		Subscription:  sc,
+		conn:          conn,
+		subOpts:       subOpts,
 		listener:      listener,
 		listenerError: listenerError,
 		done:          make(chan bool, 1),
 	}
+	client.track(&s)
 	if listener != nil {
 		runOnLoop := s.client.vu.RegisterCallback()
 		go s.handle(runOnLoop)
@@ -33,6 +43,61 @@ func NewSubscription(client *Client, sc *stomp.Subscription, listener Listener,
 	return &s
 }
 
+// current returns the active go-stomp subscription and its connection.
+func (s *Subscription) current() (*stomp.Subscription, *stomp.Conn) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.Subscription, s.conn
+}
+
+// restore subscribes again on conn with the original destination, ack mode, id and headers.
+func (s *Subscription) restore(conn *stomp.Conn) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sc, err := conn.Subscribe(s.Subscription.Destination(), s.Subscription.AckMode(), s.subOpts...)
+	if err != nil {
+		return err
+	}
+	s.Subscription, s.conn = sc, conn
+	return nil
+}
+
+func (s *Subscription) canReconnect() bool {
+	s.mu.RLock()
+	unsubscribed := s.unsubscribed
+	s.mu.RUnlock()
+	return !unsubscribed && s.client.canReconnect()
+}
+
+// tryReconnect restores the subscription on a new connection after conn was lost.
+// It must be called on the event loop, which is blocked until reconnected.
+func (s *Subscription) tryReconnect(conn *stomp.Conn) bool {
+	if !s.canReconnect() {
+		return false
+	}
+	return s.client.reconnectOnLoop(conn) == nil
+}
+
+func (s *Subscription) Active() bool {
+	sc, _ := s.current()
+	return sc.Active()
+}
+
+func (s *Subscription) Id() string {
+	sc, _ := s.current()
+	return sc.Id()
+}
+
+func (s *Subscription) Destination() string {
+	sc, _ := s.current()
+	return sc.Destination()
+}
+
+func (s *Subscription) AckMode() stomp.AckMode {
+	sc, _ := s.current()
+	return sc.AckMode()
+}
+
 func (s *Subscription) Continue() error {
 	if s.listener == nil {
 		return nil
@@ -48,9 +113,30 @@ func (s *Subscription) Continue() error {
 func (s *Subscription) handle(runOnLoop func(func() error)) {
 	noop := func() error { return nil }
 	startedAt := time.Now()
+	sc, conn := s.current()
 	select {
-	case stompMessage, ok := <-s.C:
-		if !ok || !s.Active() {
+	case stompMessage, ok := <-sc.C:
+		if (!ok || stompMessage.Err != nil) && s.canReconnect() {
+			var lost error = stomp.ErrCompletedSubscription
+			if ok {
+				lost = stompMessage.Err
+			}
+			// the token provider is called on the event loop, the connection is dialed out of it
+			runOnLoop(func() error {
+				if !s.canReconnect() {
+					return s.handleListenerError(lost)()
+				}
+				return s.client.reconnectAsync(s.client.vu.Context(), conn, func(err error) error {
+					if err != nil || !s.canReconnect() {
+						return s.handleListenerError(lost)()
+					}
+					go s.handle(s.client.vu.RegisterCallback())
+					return nil
+				})
+			})
+			return
+		}
+		if !ok || !sc.Active() {
 			runOnLoop(s.handleListenerError(stomp.ErrCompletedSubscription))
 			return
 		}
@@ -93,22 +179,29 @@ func (s *Subscription) handle(runOnLoop func(func() error)) {
 }
 
 func (s *Subscription) Unsubscribe(opts ...func(*frame.Frame) error) error {
-	if s.Active() {
+	s.mu.Lock()
+	s.unsubscribed = true
+	s.mu.Unlock()
+	s.client.untrack(s)
+
+	sc, _ := s.current()
+	if sc.Active() {
 		if s.listener != nil {
 			s.done <- true
 		}
-		return s.Subscription.Unsubscribe(opts...)
+		return sc.Unsubscribe(opts...)
 	}
 	return nil
 }
 
 func (s *Subscription) Read() (msg *Message, err error) {
+	s.client.events.dispatch()
 	startedAt := time.Now()
 	defer func() {
 		now := time.Now()
 
 		tags := map[string]string{}
-		if msg.Message != nil {
+		if msg != nil && msg.Message != nil {
 			tags[METRIC_TAG_QUEUE] = msg.Message.Destination
 		}
 
@@ -120,7 +213,12 @@ func (s *Subscription) Read() (msg *Message, err error) {
 		}
 	}()
 	var stompMessage *stomp.Message
-	stompMessage, err = s.Subscription.Read()
+	sc, conn := s.current()
+	stompMessage, err = sc.Read()
+	if err != nil && s.tryReconnect(conn) {
+		sc, _ = s.current()
+		stompMessage, err = sc.Read()
+	}
 	if err != nil {
 		common.Throw(s.client.vu.Runtime(), err)
 	}
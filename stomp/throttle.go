@@ -0,0 +1,197 @@
+package stomp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// ThrottleOptions emulates slow clients limiting the network bandwidth and adding latency.
+type ThrottleOptions struct {
+	// ReadRate and WriteRate limit the bytes per second read from and written to the network.
+	ReadRate  int
+	WriteRate int
+	// Latency is added before each frame sent and received.
+	Latency string
+}
+
+func (t *ThrottleOptions) enabled() bool {
+	return t.ReadRate > 0 || t.WriteRate > 0 || t.Latency != ""
+}
+
+func (t *ThrottleOptions) validate() error {
+	if t.ReadRate < 0 || t.WriteRate < 0 {
+		return errors.New("throttle read_rate and write_rate should be positive")
+	}
+	if t.Latency != "" {
+		if _, err := time.ParseDuration(t.Latency); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ThrottleReadWriteClose limits the read and write rates of the connection and adds latency.
+// Throttling stops when ctx is done, so closing frames are not delayed.
+type ThrottleReadWriteClose struct {
+	io.ReadWriteCloser
+	ctx     context.Context
+	read    *rateLimiter
+	write   *rateLimiter
+	latency time.Duration
+
+	// the frame boundaries the latency is added at
+	readFrames  frameScanner
+	writeFrames frameScanner
+	// pending holds the data read after the start of the next frame
+	pending    []byte
+	pendingErr error
+}
+
+func newThrottleReadWriteClose(ctx context.Context, rwc io.ReadWriteCloser, opts *ThrottleOptions) *ThrottleReadWriteClose {
+	t := ThrottleReadWriteClose{
+		ReadWriteCloser: rwc,
+		ctx:             ctx,
+		readFrames:      frameScanner{contentLength: -1},
+		writeFrames:     frameScanner{contentLength: -1},
+	}
+	t.latency, _ = time.ParseDuration(opts.Latency)
+	if opts.ReadRate > 0 {
+		t.read = newRateLimiter(opts.ReadRate)
+	}
+	if opts.WriteRate > 0 {
+		t.write = newRateLimiter(opts.WriteRate)
+	}
+	return &t
+}
+
+// Read returns the data of one frame at most, after the latency when it starts the frame.
+// The data read after the start of the next frame is returned by the next calls.
+func (t *ThrottleReadWriteClose) Read(p []byte) (int, error) {
+	if t.latency <= 0 {
+		return t.readLimited(p)
+	}
+	var (
+		n   int
+		err error
+	)
+	if len(t.pending) > 0 {
+		n = copy(p, t.pending)
+		t.pending = t.pending[n:]
+		if len(t.pending) == 0 {
+			err, t.pendingErr = t.pendingErr, nil
+		}
+	} else {
+		n, err = t.readLimited(p)
+	}
+	if n == 0 {
+		return n, err
+	}
+	if next := t.nextFrame(&t.readFrames, p[:n]); next < n {
+		t.pending = append(append([]byte(nil), p[next:n]...), t.pending...)
+		if err != nil {
+			t.pendingErr, err = err, nil
+		}
+		n = next
+	}
+	return n, err
+}
+
+func (t *ThrottleReadWriteClose) readLimited(p []byte) (int, error) {
+	if t.read != nil && len(p) > t.read.chunk {
+		// small reads leave the data in the socket buffers, slowing down the sender
+		p = p[:t.read.chunk]
+	}
+	n, err := t.ReadWriteCloser.Read(p)
+	if n > 0 && t.read != nil {
+		t.sleep(t.read.reserve(n))
+	}
+	return n, err
+}
+
+// Write adds the latency before each frame of p.
+func (t *ThrottleReadWriteClose) Write(p []byte) (int, error) {
+	if t.latency <= 0 {
+		return t.writeLimited(p)
+	}
+	var written int
+	for len(p) > 0 {
+		next := t.nextFrame(&t.writeFrames, p)
+		n, err := t.writeLimited(p[:next])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[next:]
+	}
+	return written, nil
+}
+
+// nextFrame returns the offset of the next frame starting in p, or len(p), after
+// waiting the latency when a frame starts at the beginning of p.
+func (t *ThrottleReadWriteClose) nextFrame(s *frameScanner, p []byte) int {
+	if n := s.frameStart(p); n > 0 {
+		return n
+	}
+	t.sleep(t.latency)
+	s.scan(p[:1])
+	return 1 + s.frameStart(p[1:])
+}
+
+func (t *ThrottleReadWriteClose) writeLimited(p []byte) (int, error) {
+	if t.write == nil {
+		return t.ReadWriteCloser.Write(p)
+	}
+	var written int
+	for len(p) > 0 {
+		chunk := p[:min(len(p), t.write.chunk)]
+		t.sleep(t.write.reserve(len(chunk)))
+		n, err := t.ReadWriteCloser.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+func (t *ThrottleReadWriteClose) sleep(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-t.ctx.Done():
+	}
+}
+
+// rateLimiter spaces the transfers to keep the bytes per second rate.
+type rateLimiter struct {
+	mu   sync.Mutex
+	rate float64
+	next time.Time
+	// chunk is the maximum bytes transferred at once, 1/10 of the rate
+	chunk int
+}
+
+func newRateLimiter(bytesPerSecond int) *rateLimiter {
+	return &rateLimiter{rate: float64(bytesPerSecond), chunk: max(bytesPerSecond/10, 1)}
+}
+
+// reserve accounts n bytes and returns the time to wait before the transfer is in the rate.
+func (l *rateLimiter) reserve(n int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(time.Duration(float64(n) / l.rate * float64(time.Second)))
+	return wait
+}
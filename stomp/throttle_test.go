@@ -0,0 +1,101 @@
+package stomp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// recordReadWriteClose reads from r and records each write.
+type recordReadWriteClose struct {
+	r      io.Reader
+	writes []string
+}
+
+func (rec *recordReadWriteClose) Read(p []byte) (int, error) { return rec.r.Read(p) }
+
+func (rec *recordReadWriteClose) Write(p []byte) (int, error) {
+	rec.writes = append(rec.writes, string(p))
+	return len(p), nil
+}
+
+func (rec *recordReadWriteClose) Close() error { return nil }
+
+// chunkReader returns at most size bytes per read.
+type chunkReader struct {
+	data []byte
+	size int
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	if len(c.data) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p[:min(len(p), c.size)], c.data)
+	c.data = c.data[n:]
+	return n, nil
+}
+
+func TestThrottleLatencyPerFrame(t *testing.T) {
+	body := "a\nb\x00c"
+	frames := []string{
+		"\nSEND\ndestination:/q\n\nhello\x00",
+		"\r\nSEND\ncontent-length:" + strconv.Itoa(len(body)) + "\n\n" + body + "\x00\n",
+		"MESSAGE\n\n\x00",
+	}
+	stream := frames[0] + frames[1] + frames[2]
+	const latency = 10 * time.Millisecond
+	opts := ThrottleOptions{Latency: latency.String()}
+
+	for _, size := range []int{1, 7, len(stream)} {
+		rec := &recordReadWriteClose{r: &chunkReader{data: []byte(stream), size: size}}
+		throttle := newThrottleReadWriteClose(context.Background(), rec, &opts)
+
+		startedAt := time.Now()
+		if n, err := throttle.Write([]byte(stream)); err != nil || n != len(stream) {
+			t.Fatalf("%d bytes reads: write returned %d, %v", size, n, err)
+		}
+		if elapsed := time.Since(startedAt); elapsed < 3*latency {
+			t.Errorf("%d bytes reads: 3 frames written in %s, want at least %s", size, elapsed, 3*latency)
+		}
+		want := []string{"\n", "SEND\ndestination:/q\n\nhello\x00\r\n", "SEND\ncontent-length:" + strconv.Itoa(len(body)) + "\n\n" + body + "\x00\n", "MESSAGE\n\n\x00"}
+		if !slices.Equal(rec.writes, want) {
+			t.Errorf("%d bytes reads: got writes %q, want %q", size, rec.writes, want)
+		}
+
+		startedAt = time.Now()
+		var (
+			got   bytes.Buffer
+			reads int
+		)
+		buf := make([]byte, 4096)
+		for {
+			n, err := throttle.Read(buf)
+			got.Write(buf[:n])
+			if n > 0 {
+				reads++
+			}
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got.String() != stream {
+			t.Errorf("%d bytes reads: got %q, want %q", size, got.String(), stream)
+		}
+		if elapsed := time.Since(startedAt); elapsed < 3*latency {
+			t.Errorf("%d bytes reads: 3 frames read in %s, want at least %s", size, elapsed, 3*latency)
+		}
+		// the heart-beat read before the first frame is returned on its own
+		if size == len(stream) && reads != len(frames)+1 {
+			t.Errorf("got %d reads, want one per frame (%d) and the leading heart-beat", reads, len(frames))
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package stomp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.k6.io/k6/lib"
+)
+
+// TLSConfig holds the TLS settings applied to tcp (tls: true) and wss connections.
+type TLSConfig struct {
+	// CACert is a PEM encoded CA bundle used to verify the broker certificate.
+	CACert string `js:"ca_cert"`
+	// CAFile is the path of a PEM encoded CA bundle.
+	CAFile string `js:"ca_file"`
+	// Cert and Key are the PEM encoded client certificate and private key used for mutual TLS.
+	Cert string
+	Key  string
+	// CertFile and KeyFile are the paths of the client certificate and private key.
+	CertFile string
+	KeyFile  string
+
+	ServerName   string
+	MinVersion   string
+	MaxVersion   string
+	CipherSuites []string
+
+	InsecureSkipVerify bool
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+func parseTLSVersion(v string) (uint16, error) {
+	version, ok := tlsVersions[strings.TrimPrefix(strings.ToLower(v), "tls")]
+	if !ok {
+		return 0, fmt.Errorf("invalid TLS version %q, should be '1.0', '1.1', '1.2' or '1.3'", v)
+	}
+	return version, nil
+}
+
+func parseCipherSuite(name string) (uint16, error) {
+	for _, cs := range tls.CipherSuites() {
+		if cs.Name == name {
+			return cs.ID, nil
+		}
+	}
+	for _, cs := range tls.InsecureCipherSuites() {
+		if cs.Name == name {
+			return cs.ID, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown TLS cipher suite %q", name)
+}
+
+// newTLSConfig builds the crypto/tls configuration from the connection options.
+// When called inside a VU the k6 global TLS settings (tlsAuth, tlsVersion,
+// tlsCipherSuites, insecureSkipTLSVerify) are used as the base configuration.
+func newTLSConfig(opts *Options, state *lib.State) (*tls.Config, error) {
+	cfg := new(tls.Config)
+	if state != nil && state.TLSConfig != nil {
+		cfg = state.TLSConfig.Clone()
+	}
+	if opts.TLSConfig.ServerName != "" {
+		cfg.ServerName = opts.TLSConfig.ServerName
+	}
+	if opts.InsecureSkipTLSVerify || opts.TLSConfig.InsecureSkipVerify {
+		cfg.InsecureSkipVerify = true //nolint:gosec
+	}
+	return cfg, opts.TLSConfig.apply(cfg)
+}
+
+func (t *TLSConfig) apply(cfg *tls.Config) error {
+	if t.CACert != "" || t.CAFile != "" {
+		pool := x509.NewCertPool()
+		caCert := []byte(t.CACert)
+		if t.CAFile != "" {
+			b, err := os.ReadFile(t.CAFile)
+			if err != nil {
+				return fmt.Errorf("reading CA file: %w", err)
+			}
+			caCert = append(caCert, '\n')
+			caCert = append(caCert, b...)
+		}
+		if !pool.AppendCertsFromPEM(caCert) {
+			return fmt.Errorf("no valid CA certificate found")
+		}
+		cfg.RootCAs = pool
+	}
+
+	switch {
+	case t.Cert != "" || t.Key != "":
+		cert, err := tls.X509KeyPair([]byte(t.Cert), []byte(t.Key))
+		if err != nil {
+			return fmt.Errorf("loading client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	case t.CertFile != "" || t.KeyFile != "":
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return fmt.Errorf("loading client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if t.MinVersion != "" {
+		version, err := parseTLSVersion(t.MinVersion)
+		if err != nil {
+			return err
+		}
+		cfg.MinVersion = version
+	}
+	if t.MaxVersion != "" {
+		version, err := parseTLSVersion(t.MaxVersion)
+		if err != nil {
+			return err
+		}
+		cfg.MaxVersion = version
+	}
+	if len(t.CipherSuites) > 0 {
+		cfg.CipherSuites = make([]uint16, 0, len(t.CipherSuites))
+		for _, name := range t.CipherSuites {
+			id, err := parseCipherSuite(name)
+			if err != nil {
+				return err
+			}
+			cfg.CipherSuites = append(cfg.CipherSuites, id)
+		}
+	}
+	return nil
+}
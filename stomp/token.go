@@ -0,0 +1,104 @@
+package stomp
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-stomp/stomp/v3"
+)
+
+const (
+	tokenTargetPasscode = "passcode"
+	tokenTargetHeader   = "header"
+	tokenTargetWSHeader = "ws_header"
+
+	defaultTokenHeader = "Authorization"
+	defaultTokenPrefix = "Bearer "
+)
+
+// TokenOptions sends a token, like an OAuth2 or JWT access token, on each connect and reconnect.
+type TokenOptions struct {
+	// Value is a static token.
+	Value string
+	// Provider returns the token. It's called on the event loop before each connect and reconnect.
+	Provider func() (string, error)
+	// Target is where the token is sent: passcode (default), header (CONNECT frame)
+	// or ws_header (WebSocket and SockJS handshake).
+	Target string
+	// Header is the header name for the header and ws_header targets, defaults to Authorization.
+	Header string
+	// Prefix is prepended to the token sent in headers, defaults to "Bearer " for the Authorization header.
+	Prefix string
+}
+
+func (t *TokenOptions) enabled() bool {
+	return t.Value != "" || t.Provider != nil
+}
+
+func (t *TokenOptions) validate(protocol string) error {
+	if !t.enabled() {
+		return nil
+	}
+	switch t.Target {
+	case "":
+		t.Target = tokenTargetPasscode
+	case tokenTargetPasscode, tokenTargetHeader:
+	case tokenTargetWSHeader:
+		if protocol != "ws" && protocol != "wss" && protocol != "sockjs" {
+			return fmt.Errorf("token target %q requires the ws, wss or sockjs protocol", t.Target)
+		}
+	default:
+		return fmt.Errorf("token target should be '%s', '%s' or '%s'", tokenTargetPasscode, tokenTargetHeader, tokenTargetWSHeader)
+	}
+	if t.Header == "" {
+		t.Header = defaultTokenHeader
+	}
+	if t.Prefix == "" && strings.EqualFold(t.Header, defaultTokenHeader) {
+		t.Prefix = defaultTokenPrefix
+	}
+	return nil
+}
+
+// refreshToken calls the token provider, it must be called on the event loop.
+func (c *Client) refreshToken() error {
+	t := &c.opts.Token
+	if t.Provider == nil {
+		return nil
+	}
+	token, err := t.Provider()
+	if err != nil {
+		return fmt.Errorf("token provider: %w", err)
+	}
+	if token == "" {
+		return errors.New("token provider returned an empty token")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token
+	return nil
+}
+
+func (c *Client) currentToken() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.token != "" {
+		return c.token
+	}
+	return c.opts.Token.Value
+}
+
+// tokenConnOpts returns the CONNECT options sending the current token.
+func (c *Client) tokenConnOpts() []func(*stomp.Conn) error {
+	t := &c.opts.Token
+	if !t.enabled() {
+		return nil
+	}
+	switch t.Target {
+	case tokenTargetPasscode:
+		return []func(*stomp.Conn) error{stomp.ConnOpt.Login(c.opts.User, c.currentToken())}
+	case tokenTargetHeader:
+		return []func(*stomp.Conn) error{stomp.ConnOpt.Header(t.Header, t.Prefix+c.currentToken())}
+	}
+	return nil
+}
@@ -4,7 +4,8 @@ import (
 	"time"
 
 	"github.com/go-stomp/stomp/v3"
-	"github.com/go-stomp/stomp/v3/frame"
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/js/common"
 	"go.k6.io/k6/metrics"
 )
 
@@ -13,7 +14,8 @@ type Transaction struct {
 	client *Client
 }
 
-func (tx *Transaction) Send(destination, contentType string, body []byte, opts *SendOptions) (err error) {
+// Send sends a message within the transaction. The body is a string, an ArrayBuffer or a typed array.
+func (tx *Transaction) Send(destination, contentType string, v sobek.Value, opts *SendOptions) (err error) {
 	startedAt := time.Now()
 	defer func() {
 		now := time.Now()
@@ -29,28 +31,26 @@ func (tx *Transaction) Send(destination, contentType string, body []byte, opts *
 			tx.client.reportStats(tx.client.metrics.sendMessage, tags, now, 1)
 		}
 	}()
-	if opts == nil {
-		opts = new(SendOptions)
+	body, binary, err := messageBody(tx.client.vu.Runtime(), v)
+	if err == nil {
+		err = tx.client.checkBody(body)
 	}
-	var sendOpts []func(*frame.Frame) error
-	if opts.Receipt {
-		sendOpts = append(sendOpts, stomp.SendOpt.Receipt)
+	if err != nil {
+		common.Throw(tx.client.vu.Runtime(), err)
 	}
-	for k, v := range opts.Headers {
-		sendOpts = append(sendOpts, stomp.SendOpt.Header(k, v))
+	if binary && contentType == "" {
+		contentType = binaryContentType
+	}
+	if opts == nil {
+		opts = new(SendOptions)
 	}
-	err = tx.Transaction.Send(destination, contentType, body, sendOpts...)
+	err = tx.Transaction.Send(destination, contentType, body, opts.frameOptions(body, binary)...)
 	return
 }
 
 func (tx *Transaction) Ack(m *Message) error {
 	now := time.Now()
-	if m.Header.Get(frame.Id) == "" {
-		m.Header.Set(frame.Id, m.Header.Get(frame.Ack))
-	}
-	if m.Header.Get(frame.MessageId) == "" {
-		m.Header.Set(frame.MessageId, m.Header.Get(frame.Ack))
-	}
+	ackHeaders(tx.Conn().Version(), m.Header)
 
 	tags := map[string]string{}
 	if m.Message != nil {
@@ -68,12 +68,7 @@ func (tx *Transaction) Ack(m *Message) error {
 
 func (tx *Transaction) Nack(m *Message) error {
 	now := time.Now()
-	if m.Header.Get(frame.Id) == "" {
-		m.Header.Set(frame.Id, m.Header.Get(frame.Ack))
-	}
-	if m.Header.Get(frame.MessageId) == "" {
-		m.Header.Set(frame.MessageId, m.Header.Get(frame.Ack))
-	}
+	ackHeaders(tx.Conn().Version(), m.Header)
 
 	tags := map[string]string{}
 	if m.Message != nil {
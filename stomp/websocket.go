@@ -1,32 +1,104 @@
 package stomp
 
 import (
+	"bytes"
 	"context"
-	"crypto/tls"
+	"fmt"
 	"io"
 	"log"
+	"net"
+	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
+	"github.com/go-stomp/stomp/v3/frame"
 	"github.com/gorilla/websocket"
 )
 
+var defaultWSSubprotocols = []string{"v10.stomp", "v11.stomp", "v12.stomp"}
+
+// WSOptions configures the WebSocket transport.
+type WSOptions struct {
+	// Subprotocols sent in Sec-WebSocket-Protocol, defaults to v10.stomp, v11.stomp and v12.stomp.
+	Subprotocols []string
+	// MessageType of the WebSocket messages carrying STOMP frames: text (default) or binary.
+	MessageType string
+	// Compression enables the permessage-deflate extension.
+	Compression bool
+}
+
 type wsConn struct {
-	conn *websocket.Conn
+	conn        *websocket.Conn
+	messageType int
+	// reader is the current message being read
+	reader io.Reader
+	// pending holds the bytes of an incomplete STOMP frame
+	pending []byte
 }
 
-func openWSConn(opts *Options, timeout time.Duration) (*wsConn, error) {
-	u := url.URL{Scheme: opts.Protocol, Host: opts.Addr, Path: opts.Path, RawQuery: opts.Query}
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
-	headers := make(map[string][]string)
-	for k, v := range opts.Headers {
-		headers[k] = []string{v}
+func openWSConn(ctx context.Context, opts *Options, addr string, c *Client) (*wsConn, error) {
+	messageType := websocket.TextMessage
+	switch opts.WS.MessageType {
+	case "", "text":
+	case "binary":
+		messageType = websocket.BinaryMessage
+	default:
+		return nil, fmt.Errorf("ws message_type should be 'text' or 'binary'")
+	}
+	subprotocols := opts.WS.Subprotocols
+	if len(subprotocols) == 0 {
+		subprotocols = defaultWSSubprotocols
+	}
+
+	u := url.URL{Scheme: opts.Protocol, Host: addr, Path: opts.Path, RawQuery: opts.Query}
+	conn, err := dialWS(ctx, opts, u.String(), subprotocols, c)
+	if err != nil {
+		return nil, err
+	}
+	return &wsConn{conn: conn, messageType: messageType}, nil
+}
+
+// dialWS performs the WebSocket handshake using the VU dialer.
+func dialWS(ctx context.Context, opts *Options, u string, subprotocols []string, c *Client) (*websocket.Conn, error) {
+	tlsConfig, err := newTLSConfig(opts, c.vu.State())
+	if err != nil {
+		return nil, err
+	}
+	netDial := c.dialBroker
+	if opts.WS.Compression {
+		// the wire bytes are reported to compare with data_sent and data_received
+		netDial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := c.dialBroker(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			return &wireStatsConn{conn, c}, nil
+		}
 	}
 	dialer := *websocket.DefaultDialer
-	dialer.TLSClientConfig = &tls.Config{InsecureSkipVerify: opts.InsecureSkipTLSVerify}
-	conn, resp, err := dialer.DialContext(ctx, u.String(), headers)
+	// proxies are handled by dialBroker
+	dialer.Proxy = nil
+	dialer.NetDialContext = netDial
+	dialer.NetDialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := netDial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return tlsHandshake(ctx, conn, tlsConfig, addr)
+	}
+	dialer.Subprotocols = subprotocols
+	dialer.EnableCompression = opts.WS.Compression
+
+	trace := connectTraceFrom(ctx)
+	dialPhases := trace.dnsLookup + trace.connecting + trace.tlsHandshaking
+	startedAt := time.Now()
+	conn, resp, err := dialer.DialContext(ctx, u, c.httpHeaders(opts))
+	// the upgrade is the handshake time not spent dialing
+	dialPhases = trace.dnsLookup + trace.connecting + trace.tlsHandshaking - dialPhases
+	trace.wsUpgrading += time.Since(startedAt) - dialPhases
 	if err != nil {
+		trace.fail(connectPhaseWSUpgrade)
 		if err == websocket.ErrBadHandshake {
 			b, _ := io.ReadAll(resp.Body)
 			defer resp.Body.Close()
@@ -34,24 +106,118 @@ func openWSConn(opts *Options, timeout time.Duration) (*wsConn, error) {
 		}
 		return nil, err
 	}
-	return &wsConn{conn}, nil
+	return conn, nil
 }
 
+// httpHeaders returns the headers of the WebSocket and SockJS handshake requests.
+func (c *Client) httpHeaders(opts *Options) http.Header {
+	headers := make(http.Header)
+	for k, v := range opts.Headers {
+		headers[k] = []string{v}
+	}
+	if t := &opts.Token; t.enabled() && t.Target == tokenTargetWSHeader {
+		headers.Set(t.Header, t.Prefix+c.currentToken())
+	}
+	return headers
+}
+
+// Read reads from the current WebSocket message and moves to the next one
+// when it's consumed, so messages larger than p are not truncated.
 func (w *wsConn) Read(p []byte) (int, error) {
-	_, m, err := w.conn.ReadMessage()
-	if err != nil {
-		return 0, err
+	for {
+		if w.reader == nil {
+			_, r, err := w.conn.NextReader()
+			if err != nil {
+				return 0, err
+			}
+			w.reader = r
+		}
+		n, err := w.reader.Read(p)
+		if err == io.EOF {
+			w.reader = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
 	}
-	return copy(p, m), nil
 }
 
+// Write buffers p and sends every complete STOMP frame (or heart-beat) in its
+// own WebSocket message.
 func (w *wsConn) Write(p []byte) (int, error) {
-	wr, err := w.conn.NextWriter(websocket.TextMessage)
-	if err != nil {
-		return 0, err
+	w.pending = append(w.pending, p...)
+	for {
+		n := stompFrameLen(w.pending)
+		if n == 0 {
+			break
+		}
+		if err := w.conn.WriteMessage(w.messageType, w.pending[:n]); err != nil {
+			return 0, err
+		}
+		w.pending = w.pending[n:]
+	}
+	if len(w.pending) == 0 {
+		w.pending = nil
+	}
+	return len(p), nil
+}
+
+// stompFrameLen returns the length of the first complete frame or heart-beat
+// in b, or zero if b doesn't contain a complete one yet.
+func stompFrameLen(b []byte) int {
+	if len(b) == 0 {
+		return 0
+	}
+	if b[0] == '\n' {
+		return 1
+	}
+	if bytes.HasPrefix(b, []byte("\r\n")) {
+		return 2
+	}
+
+	headerEnd, contentLength := stompHeaders(b)
+	switch {
+	case headerEnd == 0:
+		return 0
+	case contentLength >= 0:
+		if end := headerEnd + contentLength + 1; len(b) >= end {
+			return end
+		}
+		return 0
+	}
+	if i := bytes.IndexByte(b[headerEnd:], 0); i >= 0 {
+		return headerEnd + i + 1
+	}
+	return 0
+}
+
+// stompHeaders returns the length of the command and headers of the frame
+// starting b, including the blank line, or zero if they are incomplete, and
+// the content-length of the frame, -1 without a valid one.
+func stompHeaders(b []byte) (int, int) {
+	headerEnd := bytes.Index(b, []byte("\n\n"))
+	if crlf := bytes.Index(b, []byte("\r\n\r\n")); crlf >= 0 && (headerEnd < 0 || crlf < headerEnd) {
+		headerEnd = crlf + 4
+	} else if headerEnd >= 0 {
+		headerEnd += 2
+	} else {
+		return 0, -1
+	}
+
+	for _, line := range bytes.Split(b[:headerEnd], []byte("\n")) {
+		name, value, ok := bytes.Cut(bytes.TrimSuffix(line, []byte("\r")), []byte(":"))
+		if !ok || string(name) != frame.ContentLength {
+			continue
+		}
+		contentLength, err := strconv.Atoi(string(value))
+		if err != nil || contentLength < 0 {
+			break
+		}
+		return headerEnd, contentLength
 	}
-	defer wr.Close()
-	return wr.Write(p)
+	return headerEnd, -1
 }
 
 func (w *wsConn) Close() error {
@@ -0,0 +1,133 @@
+package stomp
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-stomp/stomp/v3/frame"
+	"github.com/gorilla/websocket"
+)
+
+func TestStompFrameLen(t *testing.T) {
+	tests := []struct {
+		name string
+		b    string
+		want int
+	}{
+		{"empty", "", 0},
+		{"heart-beat", "\nSEND", 1},
+		{"crlf heart-beat", "\r\nSEND", 2},
+		{"incomplete headers", "SEND\ndestination:/q\n", 0},
+		{"null terminated", "SEND\ndestination:/q\n\nhello\x00\n", 27},
+		{"incomplete body", "SEND\ndestination:/q\n\nhello", 0},
+		{"crlf headers", "SEND\r\ndestination:/q\r\n\r\nhi\x00", 27},
+		{"content-length with null", "SEND\ncontent-length:3\n\na\x00b\x00", 27},
+		{"incomplete content-length", "SEND\ncontent-length:3\n\na\x00", 0},
+		{"invalid content-length", "SEND\ncontent-length:x\n\nab\x00", 26},
+		{"two frames", "SEND\n\na\x00SEND\n\nb\x00", 8},
+	}
+	for _, tt := range tests {
+		if got := stompFrameLen([]byte(tt.b)); got != tt.want {
+			t.Errorf("%s: stompFrameLen(%q) = %d, want %d", tt.name, tt.b, got, tt.want)
+		}
+	}
+}
+
+// echoWSServer returns the messages received with the same type and records them.
+type echoWSServer struct {
+	mu       sync.Mutex
+	messages [][]byte
+	types    []int
+}
+
+func (s *echoWSServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	for {
+		messageType, m, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.messages = append(s.messages, m)
+		s.types = append(s.types, messageType)
+		s.mu.Unlock()
+		if err := conn.WriteMessage(messageType, m); err != nil {
+			return
+		}
+	}
+}
+
+func TestWSConnRoundTrip(t *testing.T) {
+	// larger than the 4096 bytes buffers go-stomp reads and writes with
+	bodies := []struct {
+		body          []byte
+		contentLength bool
+	}{
+		{[]byte("hello"), false},
+		{[]byte(strings.Repeat("é€ü", 3000)), false},
+		// frames with null bytes in the body need a content-length
+		{bytes.Repeat([]byte{0, 1, 2, 0xff, '\n'}, 4000), true},
+	}
+	for _, messageType := range []int{websocket.TextMessage, websocket.BinaryMessage} {
+		server := &echoWSServer{}
+		ts := httptest.NewServer(server)
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ws := &wsConn{conn: conn, messageType: messageType}
+
+		writer := frame.NewWriter(ws)
+		reader := frame.NewReader(ws)
+		for i, b := range bodies {
+			// heart-beats are sent as their own messages
+			if err := writer.Write(nil); err != nil {
+				t.Fatal(err)
+			}
+			f := frame.New(frame.SEND, frame.Destination, "/queue/test")
+			if b.contentLength {
+				f.Header.Set(frame.ContentLength, strconv.Itoa(len(b.body)))
+			}
+			f.Body = b.body
+			if err := writer.Write(f); err != nil {
+				t.Fatal(err)
+			}
+			var got *frame.Frame
+			for got == nil {
+				if got, err = reader.Read(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if !bytes.Equal(got.Body, b.body) {
+				t.Errorf("type %d, body %d: got %d bytes, want %d", messageType, i, len(got.Body), len(b.body))
+			}
+		}
+		conn.Close()
+		ts.Close()
+
+		server.mu.Lock()
+		if len(server.messages) != 2*len(bodies) {
+			t.Errorf("type %d: got %d messages, want one per frame and heart-beat (%d)",
+				messageType, len(server.messages), 2*len(bodies))
+		}
+		for i, m := range server.messages {
+			if server.types[i] != messageType {
+				t.Errorf("type %d: message %d sent as type %d", messageType, i, server.types[i])
+			}
+			if stompFrameLen(m) != len(m) {
+				t.Errorf("type %d: message %d isn't a whole frame: %q", messageType, i, m[:min(len(m), 32)])
+			}
+		}
+		server.mu.Unlock()
+	}
+}